@@ -0,0 +1,96 @@
+package autofund
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// Outcome is the result of evaluating a policy for a card.
+type Outcome string
+
+const (
+	// Credited means the card was topped up.
+	Credited Outcome = "credited"
+	// Failed means the top-up was attempted and failed.
+	Failed Outcome = "failed"
+	// Skipped means no top-up was needed or allowed. Skips are returned by
+	// Evaluate but not written to the audit log.
+	Skipped Outcome = "skipped"
+)
+
+// Record is one automatic funding decision.
+type Record struct {
+	Time     time.Time
+	PolicyID string
+	CardID   string
+	Outcome  Outcome
+	// Amount is the credit attempted, zero for skips.
+	Amount liquidity.Money
+	// BalanceBefore is the balance that triggered the decision and
+	// BalanceAfter the balance the top-up left.
+	BalanceBefore liquidity.Money
+	BalanceAfter  liquidity.Money
+	// IdempotencyKey is the key the top-up was sent with.
+	IdempotencyKey string
+	// Reason explains a skip or failure.
+	Reason string
+}
+
+// AuditLog keeps the trail of automatic credits and failures. It also tells
+// the engine how much a card has been credited, to enforce daily caps.
+type AuditLog interface {
+	Record(ctx context.Context, r Record) error
+	// CreditedSince sums the credited amounts of a card at or after since.
+	CreditedSince(ctx context.Context, cardID string, since time.Time) (liquidity.Money, error)
+}
+
+// MemoryAuditLog is an AuditLog held in memory. Records are lost on restart,
+// and with them the daily cap counts, so use a persistent AuditLog in
+// production.
+type MemoryAuditLog struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewMemoryAuditLog returns an empty MemoryAuditLog.
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{}
+}
+
+func (l *MemoryAuditLog) Record(ctx context.Context, r Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, r)
+	return nil
+}
+
+func (l *MemoryAuditLog) CreditedSince(ctx context.Context, cardID string, since time.Time) (liquidity.Money, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var total liquidity.Money
+	for _, r := range l.records {
+		if r.CardID != cardID || r.Outcome != Credited || r.Time.Before(since) {
+			continue
+		}
+
+		var err error
+		if total, err = total.Add(r.Amount); err != nil {
+			return liquidity.Money{}, err
+		}
+	}
+
+	return total, nil
+}
+
+// Records returns the trail, oldest first.
+func (l *MemoryAuditLog) Records() []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Record(nil), l.records...)
+}
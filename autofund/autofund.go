@@ -0,0 +1,368 @@
+// Package autofund keeps cards funded automatically. Policies say when a
+// card's balance is too low and what to top it up to; an Engine evaluates
+// them on a schedule, tops up with idempotency keys and records every
+// automatic credit in an audit log.
+package autofund
+
+import (
+	"context"
+	er "errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// DefaultInterval is how often Run evaluates policies when Engine.Interval
+// is zero.
+const DefaultInterval = 10 * time.Minute
+
+// Client is the part of *liquidity.Client the engine uses.
+type Client interface {
+	GetCardContext(ctx context.Context, card string, trackingNumber string) (liquidity.CardResp, error)
+	ListAllCards(ctx context.Context, p liquidity.Params, maxItems int) ([]liquidity.D2, error)
+	GetIntegratorFloatContext(ctx context.Context, currency string) (liquidity.FloatResp, error)
+	TopUpContext(ctx context.Context, cardId string, amount liquidity.Money, opts ...liquidity.CallOption) (liquidity.CardResp, error)
+}
+
+// Policy keeps one card, or every card of a user, funded. A card policy
+// takes precedence over a user policy for the same card.
+type Policy struct {
+	ID string
+	// CardID or UserID selects the cards; exactly one must be set.
+	CardID string
+	UserID string
+
+	// Min is the balance below which a card is topped up, and Target the
+	// balance it is topped up to.
+	Min    liquidity.Money
+	Target liquidity.Money
+	// DailyCap limits the automatic credits per card per day. Zero means no
+	// limit.
+	DailyCap liquidity.Money
+	// FloatCurrency is the float the credit is drawn from. When set, a
+	// top-up is skipped if that float cannot cover it.
+	FloatCurrency string
+}
+
+func (p Policy) validate() error {
+	switch {
+	case p.ID == "":
+		return er.New("autofund: policy has no ID")
+	case (p.CardID == "") == (p.UserID == ""):
+		return fmt.Errorf("autofund: policy %s must set exactly one of CardID and UserID", p.ID)
+	case p.Min.Sign() < 0 || p.DailyCap.Sign() < 0:
+		return fmt.Errorf("autofund: policy %s has a negative amount", p.ID)
+	case p.Target.Cmp(p.Min) < 0:
+		return fmt.Errorf("autofund: policy %s has a target below its minimum", p.ID)
+	}
+
+	return nil
+}
+
+// Engine evaluates funding policies. The zero value is ready to use once
+// Client and Audit are set.
+type Engine struct {
+	Client Client
+	Audit  AuditLog
+
+	// Interval is the time between evaluations in Run. Zero means
+	// DefaultInterval.
+	Interval time.Duration
+	// Location sets where a day starts for daily caps. It defaults to UTC.
+	Location *time.Location
+	// OnError receives errors during Run. It defaults to discarding them.
+	OnError func(err error)
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	policies map[string]Policy
+	// unaudited holds, by card, a credit whose audit record failed.
+	unaudited map[string]Record
+}
+
+// New returns an Engine that tops up through client and records to audit.
+func New(client Client, audit AuditLog) *Engine {
+	return &Engine{Client: client, Audit: audit}
+}
+
+// AddPolicy adds a policy, replacing any with the same ID.
+func (e *Engine) AddPolicy(p Policy) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.policies == nil {
+		e.policies = make(map[string]Policy)
+	}
+	e.policies[p.ID] = p
+	return nil
+}
+
+// RemovePolicy removes a policy.
+func (e *Engine) RemovePolicy(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.policies, id)
+}
+
+// Policies returns the registered policies ordered by ID.
+func (e *Engine) Policies() []Policy {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	list := make([]Policy, 0, len(e.policies))
+	for _, p := range e.policies {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	return list
+}
+
+func (e *Engine) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+
+	return time.Now()
+}
+
+// Evaluate checks every policy once and tops up the cards that need it. It
+// returns a record per card evaluated, including skips, and the first error
+// that stopped a card from being evaluated. One card failing does not stop
+// the others.
+func (e *Engine) Evaluate(ctx context.Context) ([]Record, error) {
+	policies := e.Policies()
+
+	// Card policies first, so they claim their cards before user policies.
+	sort.SliceStable(policies, func(i, j int) bool {
+		return policies[i].CardID != "" && policies[j].CardID == ""
+	})
+
+	var records []Record
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// A card policy claims its card even if the card cannot be read, so a
+	// user policy never funds it instead.
+	claimed := make(map[string]string)
+	for _, p := range policies {
+		if _, ok := claimed[p.CardID]; p.CardID != "" && !ok {
+			claimed[p.CardID] = p.ID
+		}
+	}
+
+	done := make(map[string]bool)
+	for _, p := range policies {
+		cards, err := e.cards(ctx, p)
+		if err != nil {
+			fail(err)
+			continue
+		}
+
+		for _, card := range cards {
+			if owner, ok := claimed[card.CardId]; (ok && owner != p.ID) || done[card.CardId] {
+				continue
+			}
+			done[card.CardId] = true
+
+			r, err := e.fund(ctx, p, card)
+			if err != nil {
+				fail(err)
+			}
+			records = append(records, r)
+		}
+	}
+
+	return records, firstErr
+}
+
+// cards returns the cards a policy covers.
+func (e *Engine) cards(ctx context.Context, p Policy) ([]liquidity.D2, error) {
+	if p.CardID != "" {
+		res, err := e.Client.GetCardContext(ctx, p.CardID, "")
+		if err != nil {
+			return nil, fmt.Errorf("autofund: policy %s: reading card %s: %w", p.ID, p.CardID, err)
+		}
+		return []liquidity.D2{res.Data}, nil
+	}
+
+	cards, err := e.Client.ListAllCards(ctx, liquidity.Params{Id: p.UserID}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("autofund: policy %s: listing cards of user %s: %w", p.ID, p.UserID, err)
+	}
+
+	return cards, nil
+}
+
+// fund tops up one card if its policy calls for it.
+func (e *Engine) fund(ctx context.Context, p Policy, card liquidity.D2) (Record, error) {
+	now := e.now()
+	r := Record{Time: now, PolicyID: p.ID, CardID: card.CardId, Outcome: Skipped, BalanceBefore: card.Balance, BalanceAfter: card.Balance}
+
+	if status := strings.ToLower(card.Status); status == "frozen" || status == "stopped" || status == "terminated" {
+		r.Reason = "card is " + status
+		return r, nil
+	}
+
+	if card.Balance.Cmp(p.Min) >= 0 {
+		r.Reason = "balance at or above minimum"
+		return r, nil
+	}
+
+	amount, err := p.Target.Sub(card.Balance)
+	if err != nil {
+		return e.failed(ctx, r, err)
+	}
+	amount.Currency = card.Currency
+
+	if err := e.flush(ctx, card.CardId); err != nil {
+		r.Reason = "earlier credit not yet audited"
+		return r, err
+	}
+
+	day := startOfDay(now, e.Location)
+	credited, err := e.Audit.CreditedSince(ctx, card.CardId, day)
+	if err != nil {
+		return r, fmt.Errorf("autofund: reading audit log for card %s: %w", card.CardId, err)
+	}
+
+	if p.DailyCap.Sign() > 0 {
+		left, err := p.DailyCap.Sub(credited)
+		if err != nil {
+			return e.failed(ctx, r, err)
+		}
+		if left.Sign() <= 0 {
+			r.Reason = "daily cap reached"
+			return r, nil
+		}
+		if amount.Cmp(left) > 0 {
+			amount = left
+			amount.Currency = card.Currency
+		}
+	}
+
+	r.Amount = amount
+
+	if p.FloatCurrency != "" {
+		float, err := e.Client.GetIntegratorFloatContext(ctx, strings.ToUpper(p.FloatCurrency))
+		if err != nil {
+			return e.failed(ctx, r, fmt.Errorf("reading %s float: %w", p.FloatCurrency, err))
+		}
+		if float.Data.Balance.Cmp(amount) < 0 {
+			r.Reason = fmt.Sprintf("%s float of %s cannot cover %s", p.FloatCurrency, float.Data.Balance, amount)
+			r.Amount = liquidity.Money{}
+			return r, nil
+		}
+	}
+
+	// The key only changes with the balance, the day's recorded credits or
+	// the amount, so evaluating the same state again sends the same key.
+	// flush has made sure every credit so far is recorded, so a new credit
+	// never reuses the key of one the audit log missed.
+	r.IdempotencyKey = fmt.Sprintf("autofund:%s:%s:%s:%s:%s:%s", p.ID, card.CardId, day.Format("2006-01-02"), card.Balance.Amount(), credited.Amount(), amount.Amount())
+
+	res, err := e.Client.TopUpContext(ctx, card.CardId, amount, liquidity.IdempotencyKey(r.IdempotencyKey))
+	if err != nil {
+		return e.failed(ctx, r, err)
+	}
+
+	r.Outcome = Credited
+	r.BalanceAfter = res.Data.Balance
+
+	if err := e.Audit.Record(ctx, r); err != nil {
+		e.mu.Lock()
+		if e.unaudited == nil {
+			e.unaudited = make(map[string]Record)
+		}
+		e.unaudited[card.CardId] = r
+		e.mu.Unlock()
+
+		return r, fmt.Errorf("autofund: recording credit of %s to card %s: %w", amount, card.CardId, err)
+	}
+
+	return r, nil
+}
+
+// flush records a credit to the card whose audit record failed earlier.
+// Until it is recorded the card is not topped up again: the day's credits
+// would be short and the top-up would repeat the earlier idempotency key,
+// getting the earlier result back instead of a new credit.
+func (e *Engine) flush(ctx context.Context, cardID string) error {
+	e.mu.Lock()
+	r, ok := e.unaudited[cardID]
+	e.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	if err := e.Audit.Record(ctx, r); err != nil {
+		return fmt.Errorf("autofund: recording earlier credit of %s to card %s: %w", r.Amount, cardID, err)
+	}
+
+	e.mu.Lock()
+	delete(e.unaudited, cardID)
+	e.mu.Unlock()
+
+	return nil
+}
+
+// failed records a failed top-up and returns the error.
+func (e *Engine) failed(ctx context.Context, r Record, err error) (Record, error) {
+	r.Outcome = Failed
+	r.Reason = err.Error()
+
+	err = fmt.Errorf("autofund: policy %s: topping up card %s: %w", r.PolicyID, r.CardID, err)
+	if aerr := e.Audit.Record(ctx, r); aerr != nil {
+		return r, fmt.Errorf("%v (and recording it failed: %v)", err, aerr)
+	}
+
+	return r, err
+}
+
+// Run evaluates the policies immediately and then every Interval until ctx
+// is done, which it returns.
+func (e *Engine) Run(ctx context.Context) error {
+	interval := e.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := e.Evaluate(ctx); err != nil && e.OnError != nil && ctx.Err() == nil {
+			e.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
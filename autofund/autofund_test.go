@@ -0,0 +1,279 @@
+package autofund
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+	"github.com/bushaHQ/one-liquidity-go/liquiditytest"
+)
+
+func usd(s string) liquidity.Money {
+	return liquidity.MustParseMoney(s, "USD")
+}
+
+type fixture struct {
+	srv    *liquiditytest.Server
+	cl     *liquidity.Client
+	audit  *MemoryAuditLog
+	engine *Engine
+	now    time.Time
+	userID string
+}
+
+func newFixture(t *testing.T) *fixture {
+	srv := liquiditytest.NewServer()
+	t.Cleanup(srv.Close)
+
+	f := &fixture{
+		srv:   srv,
+		cl:    srv.Client(),
+		audit: NewMemoryAuditLog(),
+		now:   time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC),
+	}
+	f.engine = New(f.cl, f.audit)
+	f.engine.Now = func() time.Time { return f.now }
+
+	srv.SetFloat(usd("1000"))
+	f.userID = srv.AddUser(liquidity.CreateUserData{FirstName: "Ada", UID: "u-1"})
+
+	return f
+}
+
+func (f *fixture) card(t *testing.T) string {
+	t.Helper()
+
+	res, err := f.cl.CreateCard(liquidity.CreateCardData{UserId: f.userID, Expiry: "2099-01-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res.Data.CardId
+}
+
+func (f *fixture) balance(t *testing.T, cardID string) liquidity.Money {
+	t.Helper()
+
+	card, _ := f.srv.Card(cardID)
+	return card.Balance
+}
+
+func (f *fixture) evaluate(t *testing.T) []Record {
+	t.Helper()
+
+	records, err := f.engine.Evaluate(context.Background())
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+
+	return records
+}
+
+func TestEngine_TopsUpToTarget(t *testing.T) {
+	f := newFixture(t)
+	cardID := f.card(t)
+
+	if err := f.engine.AddPolicy(Policy{ID: "p1", CardID: cardID, Min: usd("10"), Target: usd("50")}); err != nil {
+		t.Fatal(err)
+	}
+
+	records := f.evaluate(t)
+	if len(records) != 1 || records[0].Outcome != Credited || !records[0].Amount.Equal(usd("50")) || records[0].IdempotencyKey == "" {
+		t.Fatalf("first Evaluate() = %+v, want a 50.00 USD credit", records)
+	}
+
+	if _, err := f.cl.Debit(cardID, usd("45")); err != nil {
+		t.Fatal(err)
+	}
+
+	records = f.evaluate(t)
+	if len(records) != 1 || records[0].Outcome != Credited || !records[0].Amount.Equal(usd("45")) {
+		t.Fatalf("second Evaluate() = %+v, want a 45.00 USD credit", records)
+	}
+	if got := f.balance(t, cardID); !got.Equal(usd("50")) {
+		t.Errorf("balance = %v, want 50.00 USD", got)
+	}
+
+	records = f.evaluate(t)
+	if records[0].Outcome != Skipped {
+		t.Errorf("third Evaluate() = %+v, want a skip", records)
+	}
+
+	if got := len(f.audit.Records()); got != 2 {
+		t.Errorf("audit has %d records, want 2", got)
+	}
+}
+
+func TestEngine_DailyCap(t *testing.T) {
+	f := newFixture(t)
+	cardID := f.card(t)
+
+	f.engine.AddPolicy(Policy{ID: "p1", CardID: cardID, Min: usd("10"), Target: usd("50"), DailyCap: usd("60")})
+
+	f.evaluate(t)
+	f.cl.Debit(cardID, usd("50"))
+
+	records := f.evaluate(t)
+	if records[0].Outcome != Credited || !records[0].Amount.Equal(usd("10")) {
+		t.Fatalf("Evaluate() = %+v, want a credit capped at 10.00 USD", records)
+	}
+
+	f.cl.Debit(cardID, usd("10"))
+	if records := f.evaluate(t); records[0].Outcome != Skipped || records[0].Reason != "daily cap reached" {
+		t.Fatalf("Evaluate() = %+v, want the cap reached", records)
+	}
+
+	f.now = f.now.Add(24 * time.Hour)
+	if records := f.evaluate(t); records[0].Outcome != Credited || !records[0].Amount.Equal(usd("50")) {
+		t.Errorf("next day Evaluate() = %+v, want a 50.00 USD credit", records)
+	}
+}
+
+func TestEngine_CardPolicyOverridesUserPolicy(t *testing.T) {
+	f := newFixture(t)
+	a, b := f.card(t), f.card(t)
+	stopped := f.card(t)
+	f.cl.StopCard(stopped, 1)
+
+	f.engine.AddPolicy(Policy{ID: "user", UserID: f.userID, Min: usd("5"), Target: usd("20")})
+	f.engine.AddPolicy(Policy{ID: "vip", CardID: b, Min: usd("5"), Target: usd("100")})
+
+	records := f.evaluate(t)
+	if len(records) != 3 {
+		t.Fatalf("Evaluate() = %+v, want 3 records", records)
+	}
+
+	if got := f.balance(t, a); !got.Equal(usd("20")) {
+		t.Errorf("card a balance = %v, want 20.00 USD", got)
+	}
+	if got := f.balance(t, b); !got.Equal(usd("100")) {
+		t.Errorf("card b balance = %v, want 100.00 USD", got)
+	}
+	if got := f.balance(t, stopped); !got.IsZero() {
+		t.Errorf("stopped card balance = %v, want zero", got)
+	}
+}
+
+func TestEngine_UnreadableCardStaysClaimed(t *testing.T) {
+	f := newFixture(t)
+	cardID := f.card(t)
+	f.srv.AddRule(liquiditytest.Rule{Method: http.MethodGet, Path: "/card/v1", Fault: liquiditytest.ServerError()})
+
+	f.engine.AddPolicy(Policy{ID: "user", UserID: f.userID, Min: usd("5"), Target: usd("20")})
+	f.engine.AddPolicy(Policy{ID: "vip", CardID: cardID, Min: usd("5"), Target: usd("100")})
+
+	records, err := f.engine.Evaluate(context.Background())
+	if err == nil || len(records) != 0 {
+		t.Fatalf("Evaluate() = %+v, %v; want the card policy's error and no records", records, err)
+	}
+	if got := f.balance(t, cardID); !got.IsZero() {
+		t.Errorf("balance = %v, want the user policy to leave the card alone", got)
+	}
+}
+
+func TestEngine_FloatTooLow(t *testing.T) {
+	f := newFixture(t)
+	cardID := f.card(t)
+	f.srv.SetFloat(usd("30"))
+
+	f.engine.AddPolicy(Policy{ID: "p1", CardID: cardID, Min: usd("10"), Target: usd("50"), FloatCurrency: "USD"})
+
+	if records := f.evaluate(t); records[0].Outcome != Skipped || !f.balance(t, cardID).IsZero() {
+		t.Errorf("Evaluate() = %+v, want a skip", records)
+	}
+}
+
+func TestEngine_FailureIsAudited(t *testing.T) {
+	f := newFixture(t)
+	cardID := f.card(t)
+	f.srv.AddRule(liquiditytest.Rule{Method: http.MethodPatch, Path: "/card/v1/credit/balance", Fault: liquiditytest.ServerError()})
+
+	f.engine.AddPolicy(Policy{ID: "p1", CardID: cardID, Min: usd("10"), Target: usd("50")})
+
+	records, err := f.engine.Evaluate(context.Background())
+	if err == nil || len(records) != 1 || records[0].Outcome != Failed {
+		t.Fatalf("Evaluate() = %+v, %v; want a failure", records, err)
+	}
+
+	audit := f.audit.Records()
+	if len(audit) != 1 || audit[0].Outcome != Failed || audit[0].Reason == "" {
+		t.Errorf("audit = %+v, want the failure", audit)
+	}
+}
+
+// flakyAudit fails to record the first credit.
+type flakyAudit struct {
+	*MemoryAuditLog
+	failed bool
+}
+
+func (a *flakyAudit) Record(ctx context.Context, r Record) error {
+	if r.Outcome == Credited && !a.failed {
+		a.failed = true
+		return errors.New("audit unavailable")
+	}
+
+	return a.MemoryAuditLog.Record(ctx, r)
+}
+
+func TestEngine_UnauditedCreditNotReplayed(t *testing.T) {
+	f := newFixture(t)
+	cardID := f.card(t)
+	f.engine.Audit = &flakyAudit{MemoryAuditLog: f.audit}
+
+	f.engine.AddPolicy(Policy{ID: "p1", CardID: cardID, Min: usd("10"), Target: usd("50")})
+
+	if _, err := f.engine.Evaluate(context.Background()); err == nil {
+		t.Fatal("Evaluate() succeeded despite the audit failure")
+	}
+
+	// back to the balance, and so the key, of the first credit
+	if _, err := f.cl.Debit(cardID, usd("50")); err != nil {
+		t.Fatal(err)
+	}
+
+	records := f.evaluate(t)
+	if records[0].Outcome != Credited || !f.balance(t, cardID).Equal(usd("50")) {
+		t.Errorf("Evaluate() = %+v, balance %v; want a new 50.00 USD credit", records, f.balance(t, cardID))
+	}
+
+	audit := f.audit.Records()
+	if len(audit) != 2 || audit[0].IdempotencyKey == audit[1].IdempotencyKey {
+		t.Errorf("audit = %+v, want both credits with distinct keys", audit)
+	}
+}
+
+func TestEngine_ZeroValue(t *testing.T) {
+	var e Engine
+	e.RemovePolicy("p1")
+	if err := e.AddPolicy(Policy{ID: "p1", CardID: "c", Target: usd("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := e.Policies(); len(got) != 1 || got[0].ID != "p1" {
+		t.Errorf("Policies() = %+v, want p1", got)
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Policy
+	}{
+		{"no ID", Policy{CardID: "c", Target: usd("1")}},
+		{"no selector", Policy{ID: "p", Target: usd("1")}},
+		{"both selectors", Policy{ID: "p", CardID: "c", UserID: "u", Target: usd("1")}},
+		{"target below min", Policy{ID: "p", CardID: "c", Min: usd("10"), Target: usd("5")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := New(nil, nil).AddPolicy(tt.p); err == nil {
+				t.Error("AddPolicy() succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Package cassette records the client's HTTP traffic to files and replays it,
+// so tests can run against captured sandbox responses without the network.
+//
+// A Recorder wraps a real liquidity.HTTPClient and writes every exchange to a
+// cassette file with credentials, card numbers and CVVs scrubbed. A Replayer
+// answers requests from that file and fails on any request it has no
+// recording for. Both satisfy liquidity.HTTPClient:
+//
+//	rec := cassette.NewRecorder("testdata/cards.json", http.DefaultClient)
+//	client.SetHTTPClient(rec)
+//	...
+//	err := rec.Save()
+//
+//	rep, err := cassette.NewReplayer("testdata/cards.json")
+//	client.SetHTTPClient(rep)
+package cassette
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// scrubbedFields are the lower-cased JSON keys whose values are never written
+// to a cassette.
+var scrubbedFields = map[string]bool{
+	"cardnumber": true,
+	"pan":        true,
+	"cvv2":       true,
+	"cvv":        true,
+}
+
+// scrubbedHeaders are never written to a cassette.
+var scrubbedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Save writes the cassette to path, creating its directory if needed. The
+// file is replaced atomically.
+func (c *Cassette) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// key identifies a request for matching: its method, path, query with sorted
+// keys and scrubbed, re-encoded body.
+type key struct {
+	method string
+	path   string
+	query  string
+	body   string
+}
+
+func (k key) String() string {
+	s := k.method + " " + k.path
+	if k.query != "" {
+		s += "?" + k.query
+	}
+	if k.body != "" {
+		s += " " + k.body
+	}
+
+	return s
+}
+
+func keyOf(method, rawURL, body string) (key, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return key{}, err
+	}
+
+	return key{
+		method: strings.ToUpper(method),
+		path:   u.Path,
+		query:  u.Query().Encode(),
+		body:   normalize(body),
+	}, nil
+}
+
+// normalize scrubs a JSON body and re-encodes it with sorted keys, so bodies
+// that differ only in layout or field order match. Other bodies are left as
+// they are.
+func normalize(body string) string {
+	if doc, ok := decode(body); ok {
+		b, err := json.Marshal(scrubDoc(doc))
+		if err == nil {
+			return string(b)
+		}
+	}
+
+	return body
+}
+
+// scrub masks card data in a JSON body. Bodies that are not JSON are kept.
+func scrub(body []byte) string {
+	doc, ok := decode(string(body))
+	if !ok {
+		return string(body)
+	}
+
+	b, err := json.Marshal(scrubDoc(doc))
+	if err != nil {
+		return string(body)
+	}
+
+	return string(b)
+}
+
+func decode(body string) (interface{}, bool) {
+	if strings.TrimSpace(body) == "" {
+		return nil, false
+	}
+
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, false
+	}
+
+	return doc, true
+}
+
+func scrubDoc(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if scrubbedFields[strings.ToLower(k)] {
+				v[k] = redacted
+				continue
+			}
+			v[k] = scrubDoc(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = scrubDoc(val)
+		}
+	}
+
+	return doc
+}
+
+func scrubHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range scrubbedHeaders {
+		if h.Get(name) != "" {
+			h.Set(name, redacted)
+		}
+	}
+
+	return h
+}
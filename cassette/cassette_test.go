@@ -0,0 +1,132 @@
+package cassette
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+	"github.com/bushaHQ/one-liquidity-go/liquiditytest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cards.json")
+
+	srv := liquiditytest.NewServer()
+	defer srv.Close()
+
+	srv.SetFloat(liquidity.MustParseMoney("50", "USD"))
+	userID := srv.AddUser(liquidity.CreateUserData{FirstName: "Ada", UID: "u-1"})
+
+	rec := NewRecorder(path, http.DefaultClient)
+	cl := srv.Client(liquidity.WithHTTPClient(rec))
+
+	created, err := cl.CreateCard(liquidity.CreateCardData{UserId: userID, Expiry: "2099-01-01"})
+	if err != nil {
+		t.Fatalf("CreateCard() error = %v", err)
+	}
+	cardID := created.Data.CardId
+
+	if _, err := cl.TopUp(cardID, liquidity.MustParseMoney("10", "USD")); err != nil {
+		t.Fatalf("TopUp() error = %v", err)
+	}
+	recorded, err := cl.GetCard(cardID, "")
+	if err != nil {
+		t.Fatalf("GetCard() error = %v", err)
+	}
+	if recorded.Data.CardNumber == redacted {
+		t.Error("recorder scrubbed the response returned to the caller")
+	}
+
+	if err := rec.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{liquiditytest.APIKey, created.Data.CardNumber, `"cvv2":"` + created.Data.Cvv2} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+
+	rep, err := NewReplayer(path)
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+
+	// The replaying client has a different key and no server behind it.
+	replay, err := liquidity.New(
+		liquidity.WithAPIKey("other"),
+		liquidity.WithBaseURL(srv.URL),
+		liquidity.WithHTTPClient(rep),
+		liquidity.WithRetryPolicy(liquidity.NoRetry),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	if _, err := replay.CreateCard(liquidity.CreateCardData{SingleUse: false, Expiry: "2099-01-01", UserId: userID}); err != nil {
+		t.Fatalf("replayed CreateCard() error = %v", err)
+	}
+	if _, err := replay.TopUp(cardID, liquidity.MustParseMoney("10.00", "USD")); err != nil {
+		t.Fatalf("replayed TopUp() error = %v", err)
+	}
+	if len(rep.Unused()) != 1 {
+		t.Errorf("Unused() = %d interactions, want 1", len(rep.Unused()))
+	}
+
+	got, err := replay.GetCard(cardID, "")
+	if err != nil {
+		t.Fatalf("replayed GetCard() error = %v", err)
+	}
+	if !got.Data.Balance.Equal(recorded.Data.Balance) || got.Data.CardNumber != redacted {
+		t.Errorf("replayed GetCard() = %+v, want balance %v and a scrubbed card number", got.Data, recorded.Data.Balance)
+	}
+
+	if _, err := replay.GetCard(cardID, ""); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("second replayed GetCard() error = %v, want ErrNoMatch", err)
+	}
+	if _, err := replay.Debit(cardID, liquidity.MustParseMoney("1", "USD")); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("unrecorded Debit() error = %v, want ErrNoMatch", err)
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b [3]string
+		same bool
+	}{
+		{"query order", [3]string{"GET", "http://x/cards/v1?b=2&a=1", ""}, [3]string{"GET", "http://x/cards/v1?a=1&b=2", ""}, true},
+		{"body layout", [3]string{"PATCH", "http://x/p", `{"a":1,"b":"x"}`}, [3]string{"PATCH", "http://x/p", "{ \"b\": \"x\",\n \"a\": 1 }"}, true},
+		{"scrubbed field", [3]string{"POST", "http://x/p", `{"cvv2":"[REDACTED]"}`}, [3]string{"POST", "http://x/p", `{"cvv2":"123"}`}, true},
+		{"host ignored", [3]string{"GET", "http://x/p", ""}, [3]string{"GET", "https://y/p", ""}, true},
+		{"query value", [3]string{"GET", "http://x/p?a=1", ""}, [3]string{"GET", "http://x/p?a=2", ""}, false},
+		{"body value", [3]string{"PATCH", "http://x/p", `{"amount":10}`}, [3]string{"PATCH", "http://x/p", `{"amount":10.5}`}, false},
+		{"method", [3]string{"GET", "http://x/p", ""}, [3]string{"POST", "http://x/p", ""}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := keyOf(tt.a[0], tt.a[1], tt.a[2])
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := keyOf(tt.b[0], tt.b[1], tt.b[2])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (a == b) != tt.same {
+				t.Errorf("keyOf() = %v and %v, same = %v, want %v", a, b, a == b, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package cassette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// Recorder is a liquidity.HTTPClient that passes requests to another client
+// and records them. Call Save to write the cassette.
+type Recorder struct {
+	client liquidity.HTTPClient
+	path   string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a Recorder that sends requests through client and
+// saves them to path.
+func NewRecorder(path string, client liquidity.HTTPClient) *Recorder {
+	return &Recorder{client: client, path: path}
+}
+
+// Do sends req and records the exchange. The caller receives the response
+// unscrubbed.
+func (rec *Recorder) Do(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+
+	r, err := rec.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.cassette.Interactions = append(rec.cassette.Interactions, Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: scrubHeader(req.Header),
+			Body:   scrub(reqBody),
+		},
+		Response: Response{
+			StatusCode: r.StatusCode,
+			Header:     scrubHeader(r.Header),
+			Body:       scrub(respBody),
+		},
+	})
+
+	return r, nil
+}
+
+// Interactions returns the exchanges recorded so far.
+func (rec *Recorder) Interactions() []Interaction {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return append([]Interaction(nil), rec.cassette.Interactions...)
+}
+
+// Save writes the recorded exchanges to the cassette file.
+func (rec *Recorder) Save() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return rec.cassette.Save(rec.path)
+}
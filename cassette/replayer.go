@@ -0,0 +1,119 @@
+package cassette
+
+import (
+	er "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrNoMatch is returned by Replayer.Do for a request the cassette has no
+// unused recording for.
+var ErrNoMatch = er.New("cassette: no recorded interaction matches request")
+
+// Replayer is a liquidity.HTTPClient that answers requests from a cassette.
+// Each recording is played once, in order, so a cassette of repeated calls
+// replays their responses in sequence.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	keys         []key
+	used         []bool
+}
+
+// NewReplayer loads the cassette at path.
+func NewReplayer(path string) (*Replayer, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewReplayerFromCassette(c)
+}
+
+// NewReplayerFromCassette replays an already loaded cassette.
+func NewReplayerFromCassette(c *Cassette) (*Replayer, error) {
+	rep := &Replayer{
+		interactions: c.Interactions,
+		keys:         make([]key, len(c.Interactions)),
+		used:         make([]bool, len(c.Interactions)),
+	}
+
+	for i, in := range c.Interactions {
+		k, err := keyOf(in.Request.Method, in.Request.URL, in.Request.Body)
+		if err != nil {
+			return nil, fmt.Errorf("cassette: interaction %d: %w", i, err)
+		}
+		rep.keys[i] = k
+	}
+
+	return rep, nil
+}
+
+// Do returns the first unused recording matching req's method, path, query
+// and body, or an error wrapping ErrNoMatch.
+func (rep *Replayer) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+
+	k, err := keyOf(req.Method, req.URL.String(), string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	for i, candidate := range rep.keys {
+		if rep.used[i] || candidate != k {
+			continue
+		}
+
+		rep.used[i] = true
+		res := rep.interactions[i].Response
+
+		header := res.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode)),
+			StatusCode:    res.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(res.Body)),
+			ContentLength: int64(len(res.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNoMatch, k)
+}
+
+// Unused returns the recordings that have not been played, so a test can
+// check that every expected call was made.
+func (rep *Replayer) Unused() []Interaction {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	var unused []Interaction
+	for i, in := range rep.interactions {
+		if !rep.used[i] {
+			unused = append(unused, in)
+		}
+	}
+
+	return unused
+}
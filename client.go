@@ -2,13 +2,15 @@ package liquidity
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	er "errors"
 	"io"
-	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-querystring/query"
@@ -17,8 +19,9 @@ import (
 )
 
 const (
-	defaultBaseURL = "https://sandbox-api.oneliquidity.technology"
-	defaultTimeout = 60 * time.Second
+	defaultBaseURL   = "https://sandbox-api.oneliquidity.technology"
+	defaultTimeout   = 60 * time.Second
+	defaultUserAgent = "one-liquidity-go"
 )
 
 type HTTPClient interface {
@@ -26,61 +29,148 @@ type HTTPClient interface {
 }
 
 // Client ...
+//
+// A Client is safe for concurrent use. Its configuration is fixed by New; the
+// Set methods remain for compatibility and swap the configuration atomically.
 type Client struct {
+	mu  sync.RWMutex
+	cfg config
+}
+
+// config holds everything a Client needs to make calls.
+type config struct {
 	httpClient HTTPClient
 	baseURL    string
 	apiVersion string
 	apiKey     string
+	userAgent  string
+	timeout    time.Duration
 	debug      bool
+	retry      RetryPolicy
+	logger     Logger
+
+	idempotency IdempotencyStore
 }
 
-// NewClient creates a new One-Liquidity API client with the default base URL.
-func NewClient() *Client {
-	return &Client{
+func defaultConfig() config {
+	return config{
 		httpClient: &http.Client{Timeout: defaultTimeout},
 		baseURL:    defaultBaseURL,
-		apiKey:     os.Getenv("LIQUIDITY_PRIVATE_KEY"),
-		debug:      os.Getenv("ENV") != "production",
+		userAgent:  defaultUserAgent,
+		retry:      DefaultRetryPolicy,
+		logger:     stdLogger{},
+
+		idempotency: NewMemoryIdempotencyStore(defaultIdempotencyTTL),
 	}
 }
 
+// NewClient creates a new One-Liquidity API client with the default base URL.
+// It reads the API key from LIQUIDITY_PRIVATE_KEY and enables debug logging
+// unless ENV is "production". Prefer New, which takes explicit options.
+func NewClient() *Client {
+	cfg := defaultConfig()
+	cfg.apiKey = os.Getenv("LIQUIDITY_PRIVATE_KEY")
+	cfg.debug = os.Getenv("ENV") != "production"
+
+	return &Client{cfg: cfg}
+}
+
+// config returns a snapshot of the client's configuration.
+func (cl *Client) config() config {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
+	return cl.cfg
+}
+
+// update applies fn to the client's configuration.
+func (cl *Client) update(fn func(c *config)) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	fn(&cl.cfg)
+}
+
 //SetAuth provides the client with an API key and secret.
 func (cl *Client) SetAuth(apiKey string) error {
 	if apiKey == "" {
 		return er.New("liquidity: no credentials provided")
 	}
 
-	if !strings.HasPrefix(apiKey, "Bearer ") {
-		apiKey = "Bearer " + apiKey
-	}
-
-	cl.apiKey = apiKey
+	cl.update(func(c *config) { c.apiKey = bearer(apiKey) })
 
 	return nil
 }
 
 // SetHTTPClient sets the HTTP client that will be used for API calls.
 func (cl *Client) SetHTTPClient(httpClient HTTPClient) {
-	cl.httpClient = httpClient
+	cl.update(func(c *config) { c.httpClient = httpClient })
 }
 
 // SetBaseURL overrides the default base URL. For internal use.
 func (cl *Client) SetBaseURL(baseURL string) {
-	cl.baseURL = strings.TrimRight(baseURL, "/")
+	cl.update(func(c *config) { c.baseURL = strings.TrimRight(baseURL, "/") })
 }
 
 //SetAPIVersion overrides the default base URL. For internal use.
 func (cl *Client) SetAPIVersion(version string) {
-	cl.apiVersion = version
+	cl.update(func(c *config) { c.apiVersion = version })
 }
 
 // SetDebug enables or disables debug mode. In debug mode, HTTP requests and
-// responses will be logged.
+// responses will be logged, with card data, credentials and user PII
+// redacted.
 func (cl *Client) SetDebug(debug bool) {
-	cl.debug = debug
+	cl.update(func(c *config) { c.debug = debug })
+}
+
+// SetLogger overrides where debug logs are written. By default they go to the
+// standard library's log package. A nil logger discards them.
+func (cl *Client) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+
+	cl.update(func(c *config) { c.logger = logger })
+}
+
+// SetRetryPolicy overrides the policy used to retry failed requests. Use
+// NoRetry to disable retries.
+func (cl *Client) SetRetryPolicy(policy RetryPolicy) {
+	cl.update(func(c *config) { c.retry = policy })
+}
+
+// SetIdempotencyStore overrides the store used to remember the results of
+// money-moving calls. A nil store disables local replay; the Idempotency-Key
+// header is still sent.
+func (cl *Client) SetIdempotencyStore(store IdempotencyStore) {
+	cl.update(func(c *config) { c.idempotency = store })
+}
+
+// bearer prefixes an API key with the Bearer scheme if needed.
+func bearer(apiKey string) string {
+	if !strings.HasPrefix(apiKey, "Bearer ") {
+		apiKey = "Bearer " + apiKey
+	}
+
+	return apiKey
 }
 
-func (cl *Client) get(path string, params interface{}, response interface{}) (err error) {
+// withTimeout bounds ctx by the configured per-call timeout, if any.
+func (c config) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+func (cl *Client) get(ctx context.Context, path string, params interface{}, response interface{}) (err error) {
+	c := cl.config()
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	if params != nil {
 
 		_, err = valid.ValidateStruct(params)
@@ -88,28 +178,34 @@ func (cl *Client) get(path string, params interface{}, response interface{}) (er
 			return
 		}
 
-		v, _ := query.Values(params)
-		path = path + "?" + v.Encode()
-	}
-
-	url := cl.baseURL + "/" + strings.TrimLeft(path, "/")
+		v, err := query.Values(params)
+		if err != nil {
+			return err
+		}
 
-	if cl.debug {
-		log.Printf("liquidity: Call: %s %s", "GET", url)
-		log.Printf("liquidity: Request Params: %#v", params)
+		if q := v.Encode(); q != "" {
+			path = path + "?" + q
+		}
 	}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	url := c.baseURL + "/" + strings.TrimLeft(path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 
 	if err != nil {
 		return err
 	}
 
-	return cl.request(req, response)
+	return cl.request(c, req, response, callOptions{})
 }
 
-func (cl *Client) post(path string, params interface{}, response interface{}) (err error) {
-	url := cl.baseURL + "/" + strings.TrimLeft(path, "/")
+func (cl *Client) post(ctx context.Context, path string, params interface{}, response interface{}, opts ...CallOption) (err error) {
+	c := cl.config()
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	url := c.baseURL + "/" + strings.TrimLeft(path, "/")
 
 	var req *http.Request
 	var bodyBuffered io.Reader
@@ -120,27 +216,30 @@ func (cl *Client) post(path string, params interface{}, response interface{}) (e
 			return
 		}
 
-		data, _ := json.Marshal(params)
-		bodyBuffered = bytes.NewBuffer([]byte(data))
-
-	}
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		bodyBuffered = bytes.NewReader(data)
 
-	if cl.debug {
-		log.Printf("liquidity: Call: %s %s", "POST", path)
-		log.Printf("liquidity: Request Params: %#v", params)
 	}
 
-	req, err = http.NewRequest(http.MethodPost, url, bodyBuffered)
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, bodyBuffered)
 
 	if err != nil {
 		return
 	}
 
-	return cl.request(req, response)
+	return cl.request(c, req, response, newCallOptions(opts))
 }
 
-func (cl *Client) patch(path string, params interface{}, response interface{}) (err error) {
-	url := cl.baseURL + "/" + strings.TrimLeft(path, "/")
+func (cl *Client) patch(ctx context.Context, path string, params interface{}, response interface{}, opts ...CallOption) (err error) {
+	c := cl.config()
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	url := c.baseURL + "/" + strings.TrimLeft(path, "/")
 
 	var req *http.Request
 	var bodyBuffered io.Reader
@@ -151,54 +250,153 @@ func (cl *Client) patch(path string, params interface{}, response interface{}) (
 			return
 		}
 
-		data, _ := json.Marshal(params)
-		bodyBuffered = bytes.NewBuffer([]byte(data))
-
-	}
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		bodyBuffered = bytes.NewReader(data)
 
-	if cl.debug {
-		log.Printf("liquidity: Call: %s %s", "PATCH", path)
-		log.Printf("liquidity: Request Params: %#v", params)
 	}
 
-	req, err = http.NewRequest(http.MethodPatch, url, bodyBuffered)
+	req, err = http.NewRequestWithContext(ctx, http.MethodPatch, url, bodyBuffered)
 
 	if err != nil {
 		return
 	}
 
-	return cl.request(req, response)
+	return cl.request(c, req, response, newCallOptions(opts))
 }
 
-func (cl *Client) request(req *http.Request, response interface{}) (err error) {
+func (cl *Client) request(c config, req *http.Request, response interface{}, o callOptions) (err error) {
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", cl.apiKey)
+	req.Header.Set("Authorization", c.apiKey)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	var storeKey, fp string
+
+	if key := o.idempotencyKey; key != "" {
+		req.Header.Set(idempotencyHeader, key)
+
+		if c.idempotency != nil {
+			storeKey, fp = key, fingerprint(req)
+
+			if stored, found := c.idempotency.Get(storeKey); found {
+				body, err := replay(stored, fp)
+				if err != nil {
+					return err
+				}
+				if c.debug {
+					c.logger.Debug("replaying stored response", "method", req.Method, "path", req.URL.Path)
+				}
+				return decode(body, response)
+			}
+		}
+	}
+
+	if c.debug {
+		c.logger.Debug("request", "method", req.Method, "path", req.URL.Path, "query", redactQuery(req.URL.Query()), "body", requestBody(req))
+	}
+
+	var r *http.Response
+	var start time.Time
+
+	for attempt := 1; ; attempt++ {
+		start = time.Now()
+		r, err = c.httpClient.Do(req)
+
+		if attempt >= c.retry.MaxAttempts || !c.retry.shouldRetry(req, r, err) {
+			break
+		}
 
-	r, err := cl.httpClient.Do(req)
+		wait := c.retry.backoff(attempt, r)
+
+		if r != nil {
+			io.Copy(io.Discard, r.Body)
+			r.Body.Close()
+		}
+
+		if c.debug {
+			c.logger.Debug("retrying", "method", req.Method, "path", req.URL.Path, "outcome", outcome(r, err), "latency", time.Since(start), "wait", wait, "attempt", attempt+1)
+		}
+
+		if err = sleep(req.Context(), wait); err != nil {
+			return
+		}
+
+		if req, err = rewind(req); err != nil {
+			return
+		}
+	}
 
 	if err != nil {
+		if c.debug {
+			c.logger.Error("request failed", "method", req.Method, "path", req.URL.Path, "latency", time.Since(start), "error", err)
+		}
 		return
 	}
 
 	defer r.Body.Close()
 
-	// body, _ := ioutil.ReadAll(r.Body)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
 
-	// fmt.Println(r)
-	// fmt.Println(string(body))
+	if c.debug {
+		c.logger.Debug("response", "method", req.Method, "path", req.URL.Path, "status", r.StatusCode, "latency", time.Since(start), "body", redactJSON(body))
+	}
 
 	if r.StatusCode < 200 || r.StatusCode >= 300 {
-		e := Error{}
-		err = json.NewDecoder(r.Body).Decode(&e)
+		return newAPIError(r, body)
+	}
 
-		if err != nil {
-			return err
-		}
+	if err = decode(body, response); err != nil {
+		return
+	}
 
-		return e
+	if storeKey != "" {
+		c.idempotency.Set(storeKey, storedResponse(fp, body))
 	}
 
-	err = json.NewDecoder(r.Body).Decode(response)
 	return
 }
+
+// decode unmarshals a successful response body. 204s and other empty bodies
+// leave the response untouched.
+func decode(body []byte, response interface{}) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(body, response)
+}
+
+// requestBody returns the redacted body of req for logging.
+func requestBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+
+	return redactJSON(b)
+}
+
+// outcome describes the result of an attempt for logging.
+func outcome(r *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return strconv.Itoa(r.StatusCode)
+}
@@ -0,0 +1,372 @@
+package liquidity
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestClient(mock *MockHttpClient) *Client {
+	c := NewClient()
+	c.SetDebug(false)
+	c.SetHTTPClient(mock)
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond})
+	return c
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+	}
+}
+
+func TestClient_RequestRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		call      func(c *Client) error
+		responses []func() (*http.Response, error)
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name: "retries a GET after a 503",
+			call: func(c *Client) error {
+				_, err := c.GetIntegratorFloat("USD")
+				return err
+			},
+			responses: []func() (*http.Response, error){
+				func() (*http.Response, error) { return jsonResponse(503, `{"message":"Service Unavailable"}`), nil },
+				func() (*http.Response, error) { return jsonResponse(200, `{"message":"Ok"}`), nil },
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "retries a GET after a transport error",
+			call: func(c *Client) error {
+				_, err := c.GetIntegratorFloat("USD")
+				return err
+			},
+			responses: []func() (*http.Response, error){
+				func() (*http.Response, error) { return nil, errors.New("connection reset by peer") },
+				func() (*http.Response, error) { return jsonResponse(200, `{"message":"Ok"}`), nil },
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "gives up after max attempts",
+			call: func(c *Client) error {
+				_, err := c.GetIntegratorFloat("USD")
+				return err
+			},
+			responses: []func() (*http.Response, error){
+				func() (*http.Response, error) { return jsonResponse(502, `{"message":"Bad Gateway"}`), nil },
+				func() (*http.Response, error) { return jsonResponse(502, `{"message":"Bad Gateway"}`), nil },
+				func() (*http.Response, error) { return jsonResponse(502, `{"message":"Bad Gateway"}`), nil },
+			},
+			wantCalls: 3,
+			wantErr:   true,
+		},
+		{
+			name: "does not retry a client error",
+			call: func(c *Client) error {
+				_, err := c.GetIntegratorFloat("USD")
+				return err
+			},
+			responses: []func() (*http.Response, error){
+				func() (*http.Response, error) { return jsonResponse(400, `{"message":"Bad Request"}`), nil },
+			},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name: "does not retry a PATCH by default",
+			call: func(c *Client) error {
+				_, err := c.Freeze("aa174033-fe13-4c3a-90b3-f3485a0e9c86")
+				return err
+			},
+			responses: []func() (*http.Response, error){
+				func() (*http.Response, error) { return jsonResponse(500, `{"message":"Internal Server Error"}`), nil },
+			},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			c := newTestClient(&MockHttpClient{
+				DoFunc: func(r *http.Request) (*http.Response, error) {
+					calls++
+					if calls > len(tt.responses) {
+						t.Fatalf("unexpected call %d", calls)
+					}
+					return tt.responses[calls-1]()
+				},
+			})
+			err := tt.call(c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClient_RequestReplaysBody(t *testing.T) {
+	var bodies []string
+	c := newTestClient(&MockHttpClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			b, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(b))
+			if len(bodies) == 1 {
+				return jsonResponse(502, `{"message":"Bad Gateway"}`), nil
+			}
+			return jsonResponse(200, `{"message":"Ok"}`), nil
+		},
+	})
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, RetryNonIdempotent: true})
+
+	if _, err := c.UpdateFloatDefault("f7a1"); err != nil {
+		t.Fatalf("UpdateFloatDefault() error = %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("calls = %d, want 2", len(bodies))
+	}
+	if bodies[0] != bodies[1] || bodies[1] != `{"id":"f7a1"}` {
+		t.Errorf("bodies = %q, want the same payload on every attempt", bodies)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	tests := []struct {
+		name     string
+		attempt  int
+		header   string
+		min, max time.Duration
+	}{
+		{name: "first retry", attempt: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{name: "doubles", attempt: 2, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{name: "capped at max delay", attempt: 5, min: 150 * time.Millisecond, max: 300 * time.Millisecond},
+		{name: "honours Retry-After seconds", attempt: 1, header: "2", min: 2 * time.Second, max: 2 * time.Second},
+		{name: "ignores invalid Retry-After", attempt: 1, header: "soon", min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			if tt.header != "" {
+				res.Header.Set("Retry-After", tt.header)
+			}
+			got := p.backoff(tt.attempt, res)
+			if got < tt.min || got > tt.max {
+				t.Errorf("backoff() = %s, want between %s and %s", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestClient_IdempotencyKey(t *testing.T) {
+	var keys []string
+	c := newTestClient(&MockHttpClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+			return jsonResponse(200, `{"message":"Ok","data":{"cardId":"c954e4c9-8ca8-4d1d-8ebf-bb374e9f1b1d","balance":1000}}`), nil
+		},
+	})
+
+	key := IdempotencyKey("topup-42")
+	first, err := c.TopUp("c954e4c9-8ca8-4d1d-8ebf-bb374e9f1b1d", MustParseMoney("1000", "USD"), key)
+	if err != nil {
+		t.Fatalf("TopUp() error = %v", err)
+	}
+	second, err := c.TopUp("c954e4c9-8ca8-4d1d-8ebf-bb374e9f1b1d", MustParseMoney("1000", "USD"), key)
+	if err != nil {
+		t.Fatalf("TopUp() error = %v", err)
+	}
+
+	if len(keys) != 1 || keys[0] != "topup-42" {
+		t.Errorf("sent keys = %q, want a single call with key topup-42", keys)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("replayed response = %v, want %v", second, first)
+	}
+
+	if _, err := c.TopUp("0b0b8e2c-54c3-4b5e-a0f2-1f0d0c9a1e77", MustParseMoney("1000", "USD"), key); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("TopUp() on another card error = %v, want ErrIdempotencyKeyReused", err)
+	}
+	if _, err := c.Debit("c954e4c9-8ca8-4d1d-8ebf-bb374e9f1b1d", MustParseMoney("1000", "USD"), key); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("Debit() with the same key error = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	if _, err := c.TopUp("c954e4c9-8ca8-4d1d-8ebf-bb374e9f1b1d", MustParseMoney("1000", "USD")); err != nil {
+		t.Fatalf("TopUp() error = %v", err)
+	}
+	if _, err := c.Freeze("c954e4c9-8ca8-4d1d-8ebf-bb374e9f1b1d"); err != nil {
+		t.Fatalf("Freeze() error = %v", err)
+	}
+	if len(keys) != 3 || keys[1] != "" || keys[2] != "" {
+		t.Errorf("sent keys = %q, want no key on calls without one", keys)
+	}
+}
+
+func TestClient_IdempotencyKeyRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		opts      []CallOption
+		wantCalls int
+	}{
+		{"not retried by default", RetryPolicy{MaxAttempts: 3}, []CallOption{IdempotencyKey("debit-1")}, 1},
+		{"not retried without a key", RetryPolicy{MaxAttempts: 3, RetryIdempotent: true}, nil, 1},
+		{"retried with a key when enabled", RetryPolicy{MaxAttempts: 3, RetryIdempotent: true}, []CallOption{IdempotencyKey("debit-1")}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			c := newTestClient(&MockHttpClient{
+				DoFunc: func(r *http.Request) (*http.Response, error) {
+					keys = append(keys, r.Header.Get("Idempotency-Key"))
+					if len(keys) == 1 {
+						return jsonResponse(504, `{"message":"Gateway Timeout"}`), nil
+					}
+					return jsonResponse(200, `{"message":"Ok"}`), nil
+				},
+			})
+			c.SetRetryPolicy(tt.policy)
+
+			_, err := c.Debit("c954e4c9-8ca8-4d1d-8ebf-bb374e9f1b1d", MustParseMoney("10", "USD"), tt.opts...)
+			if len(keys) != tt.wantCalls {
+				t.Fatalf("calls = %d, want %d", len(keys), tt.wantCalls)
+			}
+			if (err == nil) != (tt.wantCalls == 2) {
+				t.Errorf("Debit() error = %v", err)
+			}
+			if tt.wantCalls == 2 && keys[0] != keys[1] {
+				t.Errorf("sent keys = %q, want the same key on the retry", keys)
+			}
+		})
+	}
+}
+
+func TestClient_IdempotencyFailureNotStored(t *testing.T) {
+	calls := 0
+	c := newTestClient(&MockHttpClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			calls++
+			if calls == 1 {
+				return jsonResponse(400, `{"message":"Bad Request"}`), nil
+			}
+			return jsonResponse(200, `{"message":"Ok"}`), nil
+		},
+	})
+
+	key := IdempotencyKey("deposit-7")
+	if _, err := c.PostIntegratorDeposit(MustParseMoney("1000", "USD"), key); err == nil {
+		t.Fatalf("PostIntegratorDeposit() expected an error")
+	}
+	if _, err := c.PostIntegratorDeposit(MustParseMoney("1000", "USD"), key); err != nil {
+		t.Fatalf("PostIntegratorDeposit() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want failed calls to be sent again", calls)
+	}
+}
+
+func TestNewIdempotencyKey(t *testing.T) {
+	a, b := NewIdempotencyKey(), NewIdempotencyKey()
+	if len(a) != 36 || a == b {
+		t.Errorf("NewIdempotencyKey() = %q, %q, want distinct UUIDs", a, b)
+	}
+}
+
+func TestClient_QueryEncoding(t *testing.T) {
+	tests := []struct {
+		name      string
+		call      func(c *Client) error
+		wantPath  string
+		wantQuery url.Values
+	}{
+		{
+			name: "omits empty parameters",
+			call: func(c *Client) error {
+				_, err := c.GetCards(Params{Id: "e08078bd-9384-5b7e-93c5-76be956380fe"})
+				return err
+			},
+			wantPath:  "/cards/v1",
+			wantQuery: url.Values{"user": {"e08078bd-9384-5b7e-93c5-76be956380fe"}},
+		},
+		{
+			name: "neutralises injected parameters",
+			call: func(c *Client) error {
+				_, err := c.GetCard("aa17&trackingNumber=evil#frag", "2141 0380")
+				return err
+			},
+			wantPath:  "/card/v1",
+			wantQuery: url.Values{"card": {"aa17&trackingNumber=evil#frag"}, "trackingNumber": {"2141 0380"}},
+		},
+		{
+			name: "escapes dates and cursors",
+			call: func(c *Client) error {
+				_, err := c.GetTransaction("c954e4c9", Params{StartDate: "2022-06-01T00:00:00+01:00", Limit: 20, Lek: `{"id":"x y"}`})
+				return err
+			},
+			wantPath: "/card/v1/transactions",
+			wantQuery: url.Values{
+				"card":      {"c954e4c9"},
+				"startDate": {"2022-06-01T00:00:00+01:00"},
+				"limit":     {"20"},
+				"lek":       {`{"id":"x y"}`},
+			},
+		},
+		{
+			name: "repeats currencies",
+			call: func(c *Client) error {
+				_, err := c.GetIntegratorFloats([]string{"USD", "BTC&x=1"})
+				return err
+			},
+			wantPath:  "/integrator/v1/floats",
+			wantQuery: url.Values{"currencies": {"USD", "BTC&x=1"}},
+		},
+		{
+			name: "sends no query for empty parameters",
+			call: func(c *Client) error {
+				_, err := c.GetUser("")
+				return err
+			},
+			wantPath:  "/card/v1/user",
+			wantQuery: url.Values{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&MockHttpClient{
+				DoFunc: func(r *http.Request) (*http.Response, error) {
+					if r.URL.Path != tt.wantPath {
+						t.Errorf("Expected to request '%s', got: %s", tt.wantPath, r.URL.Path)
+					}
+					if r.URL.Fragment != "" {
+						t.Errorf("unexpected fragment %q", r.URL.Fragment)
+					}
+					if got := r.URL.Query(); !reflect.DeepEqual(got, tt.wantQuery) {
+						t.Errorf("query = %v, want %v", got, tt.wantQuery)
+					}
+					return jsonResponse(200, `{"message":"Ok"}`), nil
+				},
+			})
+			if err := tt.call(c); err != nil {
+				t.Errorf("error = %v", err)
+			}
+		})
+	}
+}
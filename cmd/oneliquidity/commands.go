@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	er "errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+type command struct {
+	help string
+	run  func(ctx context.Context, a *app, args []string) (interface{}, error)
+}
+
+// errUsage reports a mistake on the command line, already explained to the
+// user by the flag set.
+var errUsage = er.New("usage")
+
+// commands maps "<group> <command>" to its implementation.
+var commands = map[string]command{
+	"integrator register": {"register the integrator account", registerIntegrator},
+	"integrator webhook":  {"set the webhook URL", updateWebhook},
+
+	"cards create":   {"issue a virtual card to a user", createCard},
+	"cards get":      {"show a card", getCard},
+	"cards list":     {"list cards", listCards},
+	"cards freeze":   {"freeze a card", freezeCard},
+	"cards unfreeze": {"unfreeze a card", unfreezeCard},
+	"cards stop":     {"stop a card permanently", stopCard},
+	"cards topup":    {"credit a card from the float", topUp},
+	"cards debit":    {"debit a card back to the float", debit},
+
+	"users create":         {"create a card user", createUser},
+	"users get":            {"show a user and their KYC status", getUser},
+	"users update-address": {"update a user's address", updateUserAddress},
+	"users docs":           {"get a user's KYC document upload URLs", getUserDocs},
+
+	"floats list":        {"list float balances", listFloats},
+	"floats get":         {"show the float for a currency", getFloat},
+	"floats set-default": {"make a float the default", setDefaultFloat},
+
+	"deposits get":    {"show a deposit", getDeposit},
+	"deposits create": {"start a float deposit", createDeposit},
+
+	"txns list":   {"list a card's transactions", listTransactions},
+	"txns failed": {"list failed transactions, or show one with -id", failedTransactions},
+}
+
+func findCommand(group, name string) (command, bool) {
+	cmd, ok := commands[group+" "+name]
+	return cmd, ok
+}
+
+// flags returns a flag set for a command that reports mistakes on stderr.
+func (a *app) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("oneliquidity "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	return fs
+}
+
+// parse parses args, checks that the required flags were given and connects
+// the client, so "-h" works without credentials.
+func (a *app) parse(fs *flag.FlagSet, args []string, required ...string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errUsage
+	}
+
+	if fs.NArg() > 0 {
+		return usageFailure(fs, "unexpected argument %q", fs.Arg(0))
+	}
+
+	for _, name := range required {
+		if fs.Lookup(name).Value.String() == "" {
+			return usageFailure(fs, "-%s is required", name)
+		}
+	}
+
+	client, err := a.connect()
+	if err != nil {
+		return err
+	}
+	a.client = client
+
+	return nil
+}
+
+func usageFailure(fs *flag.FlagSet, format string, args ...interface{}) error {
+	fmt.Fprintf(fs.Output(), format+"\n", args...)
+	fs.Usage()
+
+	return errUsage
+}
+
+func registerIntegrator(ctx context.Context, a *app, args []string) (interface{}, error) {
+	var data liquidity.RegisterIntegratorData
+	var currencies string
+
+	fs := a.flags("integrator register")
+	fs.StringVar(&currencies, "currencies", "USD", "comma-separated float currencies")
+	fs.StringVar(&data.FirstName, "first-name", "", "contact first name")
+	fs.StringVar(&data.LastName, "last-name", "", "contact last name")
+	fs.StringVar(&data.Country, "country", "", "country")
+	fs.StringVar(&data.BusinessName, "business-name", "", "business name")
+	fs.StringVar(&data.RegistrationNumber, "registration-number", "", "business registration number")
+	fs.StringVar(&data.BusinessAddress, "business-address", "", "business address")
+	fs.StringVar(&data.Domain, "domain", "", "business domain")
+	fs.StringVar(&data.Email, "email", "", "contact email")
+	fs.StringVar(&data.WebhookUrl, "webhook", "", "webhook URL")
+	fs.StringVar(&data.ContactNumber, "contact-number", "", "contact phone number")
+	if err := a.parse(fs, args, "business-name", "email"); err != nil {
+		return nil, err
+	}
+
+	data.FloatCurrencies = splitList(currencies)
+
+	return a.client.RegisterIntegratorContext(ctx, data)
+}
+
+func updateWebhook(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("integrator webhook")
+	url := fs.String("url", "", "webhook URL")
+	if err := a.parse(fs, args, "url"); err != nil {
+		return nil, err
+	}
+
+	return a.client.UpdateWebhookContext(ctx, *url)
+}
+
+func createCard(ctx context.Context, a *app, args []string) (interface{}, error) {
+	var data liquidity.CreateCardData
+
+	fs := a.flags("cards create")
+	fs.StringVar(&data.UserId, "user", "", "user ID")
+	fs.StringVar(&data.Expiry, "expiry", "", "expiry date, YYYY-MM-DD")
+	fs.BoolVar(&data.SingleUse, "single-use", false, "issue a single-use card")
+	if err := a.parse(fs, args, "user", "expiry"); err != nil {
+		return nil, err
+	}
+
+	res, err := a.client.CreateCardContext(ctx, data)
+	a.mask(&res.Data)
+
+	return res, err
+}
+
+func getCard(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("cards get")
+	card := fs.String("card", "", "card ID")
+	tracking := fs.String("tracking", "", "tracking number")
+	if err := a.parse(fs, args, "card"); err != nil {
+		return nil, err
+	}
+
+	res, err := a.client.GetCardContext(ctx, *card, *tracking)
+	a.mask(&res.Data)
+
+	return res, err
+}
+
+func listCards(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("cards list")
+	p := paramFlags(fs)
+	fs.StringVar(&p.Id, "user", "", "only cards of this user")
+	fs.StringVar(&p.Type, "type", "", "card type")
+	all, max := allFlags(fs)
+	if err := a.parse(fs, args); err != nil {
+		return nil, err
+	}
+
+	if *all {
+		cards, err := a.client.ListAllCards(ctx, *p, *max)
+		for i := range cards {
+			a.mask(&cards[i])
+		}
+		return cards, err
+	}
+
+	res, err := a.client.GetCardsContext(ctx, *p)
+	for i := range res.Data {
+		a.mask(&res.Data[i])
+	}
+
+	return res, err
+}
+
+func freezeCard(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("cards freeze")
+	card := fs.String("card", "", "card ID")
+	if err := a.parse(fs, args, "card"); err != nil {
+		return nil, err
+	}
+
+	return a.client.FreezeContext(ctx, *card)
+}
+
+func unfreezeCard(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("cards unfreeze")
+	card := fs.String("card", "", "card ID")
+	if err := a.parse(fs, args, "card"); err != nil {
+		return nil, err
+	}
+
+	return a.client.UnfreezeContext(ctx, *card)
+}
+
+func stopCard(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("cards stop")
+	card := fs.String("card", "", "card ID")
+	reason := fs.Int("reason", 0, "reason ID")
+	if err := a.parse(fs, args, "card"); err != nil {
+		return nil, err
+	}
+
+	return a.client.StopCardContext(ctx, *card, *reason)
+}
+
+func topUp(ctx context.Context, a *app, args []string) (interface{}, error) {
+	return moveBalance(ctx, a, "cards topup", args, (*liquidity.Client).TopUpContext)
+}
+
+func debit(ctx context.Context, a *app, args []string) (interface{}, error) {
+	return moveBalance(ctx, a, "cards debit", args, (*liquidity.Client).DebitContext)
+}
+
+func moveBalance(ctx context.Context, a *app, name string, args []string, fn func(*liquidity.Client, context.Context, string, liquidity.Money, ...liquidity.CallOption) (liquidity.CardResp, error)) (interface{}, error) {
+	fs := a.flags(name)
+	card := fs.String("card", "", "card ID")
+	amount, opts := amountFlags(fs)
+	if err := a.parse(fs, args, "card", "amount"); err != nil {
+		return nil, err
+	}
+
+	m, err := amount()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := fn(a.client, ctx, *card, m, opts()...)
+	a.mask(&res.Data)
+
+	return res, err
+}
+
+func createUser(ctx context.Context, a *app, args []string) (interface{}, error) {
+	var data liquidity.CreateUserData
+
+	fs := a.flags("users create")
+	fs.StringVar(&data.FirstName, "first-name", "", "first name")
+	fs.StringVar(&data.LastName, "last-name", "", "last name")
+	fs.StringVar(&data.KycCountry, "country", "", "KYC country")
+	fs.StringVar(&data.UID, "uid", "", "your ID for the user")
+	fs.StringVar(&data.Address, "address", "", "street address")
+	fs.StringVar(&data.City, "city", "", "city")
+	fs.StringVar(&data.PostalCode, "postal-code", "", "postal code")
+	if err := a.parse(fs, args, "first-name", "last-name", "country", "uid"); err != nil {
+		return nil, err
+	}
+
+	return a.client.CreateUserContext(ctx, data)
+}
+
+func getUser(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("users get")
+	user := fs.String("user", "", "user ID")
+	if err := a.parse(fs, args, "user"); err != nil {
+		return nil, err
+	}
+
+	return a.client.GetUserContext(ctx, *user)
+}
+
+func updateUserAddress(ctx context.Context, a *app, args []string) (interface{}, error) {
+	var data liquidity.UpdateUserAddressData
+
+	fs := a.flags("users update-address")
+	fs.StringVar(&data.UserID, "user", "", "user ID")
+	fs.StringVar(&data.KycCountry, "country", "", "KYC country")
+	fs.StringVar(&data.Address, "address", "", "street address")
+	fs.StringVar(&data.City, "city", "", "city")
+	fs.StringVar(&data.PostalCode, "postal-code", "", "postal code")
+	if err := a.parse(fs, args, "user"); err != nil {
+		return nil, err
+	}
+
+	return a.client.UpdateUserAddressContext(ctx, data)
+}
+
+func getUserDocs(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("users docs")
+	user := fs.String("user", "", "user ID")
+	if err := a.parse(fs, args, "user"); err != nil {
+		return nil, err
+	}
+
+	return a.client.GetCardUserDocURLContext(ctx, *user)
+}
+
+func listFloats(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("floats list")
+	currencies := fs.String("currencies", "", "comma-separated currencies, default all")
+	if err := a.parse(fs, args); err != nil {
+		return nil, err
+	}
+
+	return a.client.GetIntegratorFloatsContext(ctx, splitList(*currencies))
+}
+
+func getFloat(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("floats get")
+	currency := fs.String("currency", "", "float currency")
+	if err := a.parse(fs, args, "currency"); err != nil {
+		return nil, err
+	}
+
+	return a.client.GetIntegratorFloatContext(ctx, strings.ToUpper(*currency))
+}
+
+func setDefaultFloat(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("floats set-default")
+	id := fs.String("id", "", "float ID")
+	if err := a.parse(fs, args, "id"); err != nil {
+		return nil, err
+	}
+
+	return a.client.UpdateFloatDefaultContext(ctx, *id)
+}
+
+func getDeposit(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("deposits get")
+	id := fs.String("id", "", "deposit ID")
+	if err := a.parse(fs, args, "id"); err != nil {
+		return nil, err
+	}
+
+	return a.client.GetIntegratorDepositContext(ctx, *id)
+}
+
+func createDeposit(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("deposits create")
+	amount, opts := amountFlags(fs)
+	if err := a.parse(fs, args, "amount"); err != nil {
+		return nil, err
+	}
+
+	m, err := amount()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.client.PostIntegratorDepositContext(ctx, m, opts()...)
+}
+
+func listTransactions(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("txns list")
+	card := fs.String("card", "", "card ID")
+	p := paramFlags(fs)
+	all, max := allFlags(fs)
+	if err := a.parse(fs, args, "card"); err != nil {
+		return nil, err
+	}
+
+	if *all {
+		return a.client.ListAllTransactions(ctx, *card, *p, *max)
+	}
+
+	return a.client.GetTransactionContext(ctx, *card, *p)
+}
+
+func failedTransactions(ctx context.Context, a *app, args []string) (interface{}, error) {
+	fs := a.flags("txns failed")
+	id := fs.String("id", "", "show this failed transaction")
+	p := paramFlags(fs)
+	fs.StringVar(&p.Id, "card", "", "only failed transactions of this card")
+	all, max := allFlags(fs)
+	if err := a.parse(fs, args); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case *id != "":
+		return a.client.GetFailedTransactionContext(ctx, *id)
+	case *all:
+		return a.client.ListAllFailedTransactions(ctx, *p, *max)
+	}
+
+	return a.client.GetFailedTransactionsContext(ctx, *p)
+}
+
+// paramFlags registers the date range and paging flags shared by the list
+// commands.
+func paramFlags(fs *flag.FlagSet) *liquidity.Params {
+	var p liquidity.Params
+
+	fs.StringVar(&p.StartDate, "start", "", "start date, YYYY-MM-DD")
+	fs.StringVar(&p.EndDate, "end", "", "end date, YYYY-MM-DD")
+	fs.IntVar(&p.Limit, "limit", 0, "page size")
+	fs.StringVar(&p.Lek, "lek", "", "cursor returned by the previous page")
+
+	return &p
+}
+
+func allFlags(fs *flag.FlagSet) (*bool, *int) {
+	all := fs.Bool("all", false, "follow the cursor and list every page")
+	max := fs.Int("max", liquidity.DefaultMaxItems, "with -all, fail past this many items")
+
+	return all, max
+}
+
+// amountFlags registers -amount, -currency and -idempotency-key. It returns
+// the parsed amount and the call options for the idempotency key.
+func amountFlags(fs *flag.FlagSet) (func() (liquidity.Money, error), func() []liquidity.CallOption) {
+	amount := fs.String("amount", "", "amount in major units, e.g. 10.50")
+	currency := fs.String("currency", "USD", "currency")
+	key := fs.String("idempotency-key", "", "reuse to retry a call safely")
+
+	parse := func() (liquidity.Money, error) {
+		return liquidity.ParseMoney(*amount, strings.ToUpper(*currency))
+	}
+
+	opts := func() []liquidity.CallOption {
+		if *key == "" {
+			return nil
+		}
+		return []liquidity.CallOption{liquidity.IdempotencyKey(*key)}
+	}
+
+	return parse, opts
+}
+
+// mask hides the card number and CVV unless -reveal was given.
+func (a *app) mask(card *liquidity.D2) {
+	if a.reveal {
+		return
+	}
+
+	if card.CardNumber != "" {
+		card.CardNumber = "**** **** **** " + card.Last4
+	}
+	if card.Cvv2 != "" {
+		card.Cvv2 = "***"
+	}
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, strings.ToUpper(item))
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	er "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// profile is a named set of credentials in the config file.
+type profile struct {
+	APIKey      string `json:"apiKey"`
+	Environment string `json:"environment,omitempty"`
+	BaseURL     string `json:"baseUrl,omitempty"`
+}
+
+// loadProfile reads the named profile. Flags win over the environment, which
+// wins over the defaults. A missing config file is fine as long as
+// LIQUIDITY_PRIVATE_KEY is set.
+func loadProfile(path, name string, getenv func(string) string) (profile, error) {
+	if name == "" {
+		name = getenv("LIQUIDITY_PROFILE")
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	if path == "" {
+		path = getenv("LIQUIDITY_CONFIG")
+	}
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			path = filepath.Join(dir, "oneliquidity", "config.json")
+		}
+	}
+
+	var p profile
+
+	profiles, err := readProfiles(path)
+	switch {
+	case err == nil:
+		found := false
+		p, found = profiles[name]
+		if !found && getenv("LIQUIDITY_PRIVATE_KEY") == "" {
+			return profile{}, fmt.Errorf("no profile %q in %s", name, path)
+		}
+	case er.Is(err, os.ErrNotExist):
+	default:
+		return profile{}, err
+	}
+
+	if key := getenv("LIQUIDITY_PRIVATE_KEY"); key != "" {
+		p.APIKey = key
+	}
+
+	if p.APIKey == "" {
+		return profile{}, fmt.Errorf("no API key: set LIQUIDITY_PRIVATE_KEY or add profile %q to %s", name, path)
+	}
+
+	return p, nil
+}
+
+func readProfiles(path string) (map[string]profile, error) {
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles map[string]profile
+	if err := json.Unmarshal(b, &profiles); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	return profiles, nil
+}
+
+// options turns the profile into client options.
+func (p profile) options() ([]liquidity.Option, error) {
+	opts := []liquidity.Option{liquidity.WithAPIKey(p.APIKey)}
+
+	switch liquidity.Environment(p.Environment) {
+	case "":
+	case liquidity.Sandbox, liquidity.Production:
+		opts = append(opts, liquidity.WithEnvironment(liquidity.Environment(p.Environment)))
+	default:
+		return nil, fmt.Errorf("unknown environment %q", p.Environment)
+	}
+
+	if p.BaseURL != "" {
+		opts = append(opts, liquidity.WithBaseURL(p.BaseURL))
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,181 @@
+// Command oneliquidity operates a One Liquidity integrator account from the
+// command line: cards, users, floats, deposits and transactions.
+//
+// Usage:
+//
+//	oneliquidity [flags] <group> <command> [command flags]
+//
+// Credentials come from a profile in the config file, by default
+// $XDG_CONFIG_HOME/oneliquidity/config.json:
+//
+//	{
+//	  "default": {"apiKey": "sk_test_...", "environment": "sandbox"},
+//	  "live":    {"apiKey": "sk_live_...", "environment": "production"}
+//	}
+//
+// LIQUIDITY_PRIVATE_KEY overrides the profile's key. Run "oneliquidity help"
+// for the list of commands.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// app is what a command needs to run.
+type app struct {
+	client  *liquidity.Client
+	connect func() (*liquidity.Client, error)
+	reveal  bool
+	stderr  io.Writer
+}
+
+// run executes the command line args and returns the exit code.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	fs := flag.NewFlagSet("oneliquidity", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(stderr, fs) }
+
+	profile := fs.String("profile", "", "credentials profile (default $LIQUIDITY_PROFILE or \"default\")")
+	configPath := fs.String("config", "", "config file (default $LIQUIDITY_CONFIG or the user config dir)")
+	output := fs.String("output", "table", "output format: table or json")
+	baseURL := fs.String("base-url", "", "override the API base URL")
+	timeout := fs.Duration("timeout", 30*time.Second, "per-request timeout")
+	reveal := fs.Bool("reveal", false, "show full card numbers and CVVs")
+	debug := fs.Bool("debug", false, "log requests and responses, redacted, to stderr")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 || fs.Arg(0) == "help" {
+		usage(stdout, fs)
+		return 0
+	}
+
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(stderr, "oneliquidity: unknown output format %q\n", *output)
+		return 2
+	}
+
+	if fs.NArg() < 2 {
+		fmt.Fprintf(stderr, "oneliquidity: missing command for %q\n", fs.Arg(0))
+		return 2
+	}
+
+	cmd, ok := findCommand(fs.Arg(0), fs.Arg(1))
+	if !ok {
+		fmt.Fprintf(stderr, "oneliquidity: unknown command %q\n", fs.Arg(0)+" "+fs.Arg(1))
+		return 2
+	}
+
+	connect := func() (*liquidity.Client, error) {
+		p, err := loadProfile(*configPath, *profile, getenv)
+		if err != nil {
+			return nil, err
+		}
+		if *baseURL != "" {
+			p.BaseURL = *baseURL
+		}
+
+		opts, err := p.options()
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, liquidity.WithTimeout(*timeout), liquidity.WithDebug(*debug))
+		if *debug {
+			opts = append(opts, liquidity.WithLogger(stderrLogger{stderr}))
+		}
+
+		return liquidity.New(opts...)
+	}
+
+	a := &app{connect: connect, reveal: *reveal, stderr: stderr}
+
+	res, err := cmd.run(ctx, a, fs.Args()[2:])
+	switch {
+	case err == flag.ErrHelp:
+		return 0
+	case err == errUsage:
+		return 2
+	case err != nil:
+		fmt.Fprintf(stderr, "oneliquidity: %v\n", err)
+		return 1
+	}
+
+	if *output == "json" {
+		err = writeJSON(stdout, res)
+	} else {
+		err = writeTable(stdout, res)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "oneliquidity: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+func usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage: oneliquidity [flags] <group> <command> [command flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-24s %s\n", name, commands[name].help)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "oneliquidity <group> <command> -h" for a command's flags.`)
+}
+
+// stderrLogger writes the client's debug logs as plain lines.
+type stderrLogger struct {
+	w io.Writer
+}
+
+func (l stderrLogger) Debug(msg string, args ...interface{}) {
+	l.write(msg, args)
+}
+
+func (l stderrLogger) Error(msg string, args ...interface{}) {
+	l.write("ERROR "+msg, args)
+}
+
+func (l stderrLogger) write(msg string, args []interface{}) {
+	var b strings.Builder
+
+	b.WriteString(msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+
+	fmt.Fprintln(l.w, b.String())
+}
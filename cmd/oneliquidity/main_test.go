@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+	"github.com/bushaHQ/one-liquidity-go/liquiditytest"
+)
+
+type cli struct {
+	t   *testing.T
+	env map[string]string
+}
+
+func newCLI(t *testing.T, srv *liquiditytest.Server) *cli {
+	config := filepath.Join(t.TempDir(), "config.json")
+	profiles := `{"default": {"apiKey": "` + liquiditytest.APIKey + `", "baseUrl": "` + srv.URL + `"}}`
+	if err := os.WriteFile(config, []byte(profiles), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return &cli{t: t, env: map[string]string{"LIQUIDITY_CONFIG": config}}
+}
+
+func (c *cli) run(args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr, func(k string) string { return c.env[k] })
+	return stdout.String(), stderr.String(), code
+}
+
+func (c *cli) mustRun(args ...string) string {
+	c.t.Helper()
+
+	stdout, stderr, code := c.run(args...)
+	if code != 0 {
+		c.t.Fatalf("%s: exit %d: %s", strings.Join(args, " "), code, stderr)
+	}
+
+	return stdout
+}
+
+func TestCards(t *testing.T) {
+	srv := liquiditytest.NewServer()
+	defer srv.Close()
+
+	srv.SetFloat(liquidity.MustParseMoney("100", "USD"))
+	userID := srv.AddUser(liquidity.CreateUserData{FirstName: "Ada", LastName: "Obi", UID: "u-1"})
+
+	c := newCLI(t, srv)
+
+	var created liquidity.CardResp
+	out := c.mustRun("-output", "json", "cards", "create", "-user", userID, "-expiry", "2099-01-01")
+	if err := json.Unmarshal([]byte(out), &created); err != nil {
+		t.Fatalf("cards create output %q: %v", out, err)
+	}
+	cardID := created.Data.CardId
+
+	if full, _ := srv.Card(cardID); strings.Contains(out, full.CardNumber) || strings.Contains(out, `"cvv2": "`+full.Cvv2) {
+		t.Errorf("cards create printed card data without -reveal: %s", out)
+	}
+
+	c.mustRun("cards", "topup", "-card", cardID, "-amount", "30")
+	c.mustRun("cards", "debit", "-card", cardID, "-amount", "5.50")
+
+	out = c.mustRun("cards", "get", "-card", cardID)
+	if !strings.Contains(out, "24.50 USD") {
+		t.Errorf("cards get = %q, want a balance of 24.50 USD", out)
+	}
+
+	out = c.mustRun("txns", "list", "-card", cardID, "-all")
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[0], "TRANSACTIONID") {
+		t.Errorf("txns list = %q, want a header and 2 rows", out)
+	}
+
+	c.mustRun("cards", "freeze", "-card", cardID)
+	if _, stderr, code := c.run("cards", "topup", "-card", cardID, "-amount", "1"); code != 1 || !strings.Contains(stderr, "frozen") {
+		t.Errorf("topup on a frozen card: exit %d, stderr %q", code, stderr)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	srv := liquiditytest.NewServer()
+	defer srv.Close()
+
+	userID := srv.AddUser(liquidity.CreateUserData{FirstName: "Ada", LastName: "Obi", UID: "u-1"})
+
+	out := newCLI(t, srv).mustRun("users", "get", "-user", userID)
+	if !strings.Contains(out, "firstName") || !strings.Contains(out, "selfieUploaded") || strings.Contains(out, "UserProfile") {
+		t.Errorf("users get = %q, want the profile and KYC fields flattened", out)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	srv := liquiditytest.NewServer()
+	defer srv.Close()
+
+	c := newCLI(t, srv)
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"help", []string{"help"}, 0},
+		{"command help needs no credentials", []string{"-profile", "missing", "cards", "topup", "-h"}, 0},
+		{"unknown command", []string{"cards", "melt"}, 2},
+		{"missing flag", []string{"cards", "get"}, 2},
+		{"bad output", []string{"-output", "xml", "floats", "list"}, 2},
+		{"unknown profile", []string{"-profile", "missing", "floats", "list"}, 1},
+		{"bad amount", []string{"cards", "topup", "-card", "c", "-amount", "1.234"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, stderr, code := c.run(tt.args...); code != tt.code {
+				t.Errorf("exit = %d, want %d; stderr %q", code, tt.code, stderr)
+			}
+		})
+	}
+}
+
+func TestProfileEnvKey(t *testing.T) {
+	p, err := loadProfile(filepath.Join(t.TempDir(), "none.json"), "", func(k string) string {
+		return map[string]string{"LIQUIDITY_PRIVATE_KEY": "sk"}[k]
+	})
+	if err != nil {
+		t.Fatalf("loadProfile() error = %v", err)
+	}
+	if p.APIKey != "sk" {
+		t.Errorf("APIKey = %q, want sk", p.APIKey)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeTable prints a response for people. The envelope is dropped: a list
+// becomes one row per item and a single record one row per field. The cursor
+// of a partial list is printed after it.
+func writeTable(w io.Writer, v interface{}) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+
+	var lek string
+	if rv.Kind() == reflect.Struct {
+		if f := rv.FieldByName("Lek"); f.IsValid() && f.Kind() == reflect.String {
+			lek = f.String()
+		}
+		if data := rv.FieldByName("Data"); data.IsValid() {
+			rv = data
+		} else if msg := rv.FieldByName("Message"); msg.IsValid() {
+			rv = msg
+		}
+	}
+
+	switch {
+	case rv.Kind() == reflect.Slice:
+		writeRows(tw, rv)
+	case rv.Kind() == reflect.Struct && !isScalar(rv):
+		writeRecord(tw, "", rv)
+	default:
+		fmt.Fprintln(tw, format(rv))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if lek != "" {
+		_, err := fmt.Fprintf(w, "\nMore results: -lek %s\n", lek)
+		return err
+	}
+
+	return nil
+}
+
+func writeRows(w io.Writer, rv reflect.Value) {
+	if rv.Len() == 0 {
+		fmt.Fprintln(w, "No results.")
+		return
+	}
+
+	elem := rv.Type().Elem()
+	if elem.Kind() != reflect.Struct || isScalar(reflect.New(elem).Elem()) {
+		for i := 0; i < rv.Len(); i++ {
+			fmt.Fprintln(w, format(rv.Index(i)))
+		}
+		return
+	}
+
+	var cols []int
+	var names []string
+	for i := 0; i < elem.NumField(); i++ {
+		f := elem.Field(i)
+		if f.PkgPath != "" || !isScalar(reflect.New(f.Type).Elem()) {
+			continue
+		}
+		cols = append(cols, i)
+		names = append(names, strings.ToUpper(fieldName(f)))
+	}
+
+	fmt.Fprintln(w, strings.Join(names, "\t"))
+
+	for i := 0; i < rv.Len(); i++ {
+		cells := make([]string, len(cols))
+		for j, c := range cols {
+			cells[j] = format(rv.Index(i).Field(c))
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+}
+
+// writeRecord prints the fields of a struct, flattening nested structs with
+// a dotted prefix and skipping empty ones. Embedded structs are flattened
+// without a prefix.
+func writeRecord(w io.Writer, prefix string, rv reflect.Value) {
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		v := rv.Field(i)
+		if f.Anonymous && v.Kind() == reflect.Struct {
+			writeRecord(w, prefix, v)
+			continue
+		}
+
+		name := prefix + fieldName(f)
+
+		if v.Kind() == reflect.Struct && !isScalar(v) {
+			if !v.IsZero() {
+				writeRecord(w, name+".", v)
+			}
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\n", name, format(v))
+	}
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// isScalar reports whether v prints as a single cell.
+func isScalar(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Struct:
+		return v.Type().Implements(stringerType)
+	case reflect.Slice:
+		return v.Type().Elem().Kind() == reflect.String
+	case reflect.Map, reflect.Interface, reflect.Ptr:
+		return false
+	}
+
+	return true
+}
+
+func format(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(parts, ",")
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+// fieldName returns the JSON name of a field.
+func fieldName(f reflect.StructField) string {
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+
+	return f.Name
+}
@@ -1,13 +1,241 @@
 package liquidity
 
-import "encoding/json"
+import (
+	"encoding/json"
+	er "errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
 
-func (e Error) Error() string {
-	marshal, _ := json.Marshal(e.ValidationError)
-	return e.Message + ": " + string(marshal)
+// Sentinel errors matched by APIError through errors.Is.
+var (
+	ErrNotFound          = er.New("liquidity: not found")
+	ErrUnauthorized      = er.New("liquidity: unauthorized")
+	ErrInsufficientFunds = er.New("liquidity: insufficient funds")
+)
+
+// requestIDHeaders are the response headers that may carry the request ID
+// assigned by One Liquidity or its gateway.
+var requestIDHeaders = []string{"X-Request-Id", "X-Amzn-Requestid", "Apigw-Requestid"}
+
+// maxSnippetLen caps how much of an error body without a message is quoted
+// in the error message.
+const maxSnippetLen = 200
+
+// APIError is returned when One Liquidity responds with a non-2xx status.
+// Bodies that are not JSON, such as an HTML page from a proxy, leave Message
+// empty and are kept in Body.
+type APIError struct {
+	StatusCode       int
+	RequestID        string
+	ContentType      string
+	Message          string
+	ValidationIssues []ValidationIssue
+	Body             []byte
 }
 
+// Error is the error type returned before APIError.
+//
+// Deprecated: use APIError. errors.As still fills an Error from an
+// *APIError, but the client no longer returns Error values, so type
+// assertions on them fail.
 type Error struct {
 	Message         string      `json:"message"`
 	ValidationError interface{} `json:"validationError"`
 }
+
+func (e Error) Error() string {
+	marshal, _ := json.Marshal(e.ValidationError)
+	return e.Message + ": " + string(marshal)
+}
+
+// ValidationIssue describes a single field rejected by the API.
+type ValidationIssue struct {
+	Code     string   `json:"code"`
+	Keys     []string `json:"keys,omitempty"`
+	Expected string   `json:"expected,omitempty"`
+	Received string   `json:"received,omitempty"`
+	Path     []string `json:"path,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("liquidity: %d %s", e.StatusCode, http.StatusText(e.StatusCode)))
+
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	} else if snippet := e.Snippet(); snippet != "" {
+		b.WriteString(": " + snippet)
+	}
+
+	for _, issue := range e.ValidationIssues {
+		b.WriteString("; ")
+		if len(issue.Path) > 0 {
+			b.WriteString(strings.Join(issue.Path, ".") + ": ")
+		}
+		b.WriteString(issue.Message)
+	}
+
+	if e.RequestID != "" {
+		b.WriteString(" (request " + e.RequestID + ")")
+	}
+
+	return b.String()
+}
+
+// Snippet returns the start of the response body, redacted, for use in logs
+// and error messages. JSON bodies are redacted as in the debug logs. Plain
+// text and HTML bodies are quoted with whitespace collapsed and anything
+// that looks like a card number masked. Other bodies are only described by
+// their size.
+func (e *APIError) Snippet() string {
+	var snippet string
+
+	mediaType, _, _ := mime.ParseMediaType(e.ContentType)
+	if (mediaType == "text/html" || mediaType == "text/plain") && !json.Valid(e.Body) {
+		snippet = redactText(strings.Join(strings.Fields(string(e.Body)), " "))
+	} else {
+		snippet = redactJSON(e.Body)
+	}
+
+	if len(snippet) > maxSnippetLen {
+		snippet = strings.ToValidUTF8(snippet[:maxSnippetLen], "") + "..."
+	}
+
+	return snippet
+}
+
+// Is reports whether e matches one of the sentinel errors. The API has no
+// error code for a short balance, so ErrInsufficientFunds matches a 402, or
+// a 400 or 422 whose message mentions "insufficient", as in "Insufficient
+// float balance".
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrInsufficientFunds:
+		switch e.StatusCode {
+		case http.StatusPaymentRequired:
+			return true
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			return strings.Contains(strings.ToLower(e.Message), "insufficient")
+		}
+	}
+
+	return false
+}
+
+// As fills a deprecated Error from e, for callers written against it.
+func (e *APIError) As(target interface{}) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	*t = Error{Message: e.Message}
+
+	var eb errorBody
+	if json.Unmarshal(e.Body, &eb) == nil && len(eb.ValidationError) > 0 {
+		json.Unmarshal(eb.ValidationError, &t.ValidationError)
+	}
+
+	return true
+}
+
+// IsNotFound reports whether err is an APIError for a missing resource.
+func IsNotFound(err error) bool {
+	return er.Is(err, ErrNotFound)
+}
+
+// IsUnauthorized reports whether err is an APIError caused by missing or
+// invalid credentials.
+func IsUnauthorized(err error) bool {
+	return er.Is(err, ErrUnauthorized)
+}
+
+// IsInsufficientFunds reports whether err is an APIError caused by a card or
+// float balance being too low for the operation.
+func IsInsufficientFunds(err error) bool {
+	return er.Is(err, ErrInsufficientFunds)
+}
+
+// UnmarshalJSON accepts paths made of both field names and array indexes.
+func (v *ValidationIssue) UnmarshalJSON(data []byte) error {
+	type issue ValidationIssue
+	var raw struct {
+		issue
+		Path []interface{} `json:"path"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*v = ValidationIssue(raw.issue)
+	v.Path = nil
+	for _, p := range raw.Path {
+		v.Path = append(v.Path, fmt.Sprint(p))
+	}
+
+	return nil
+}
+
+// errorBody is the JSON shape of an error response.
+type errorBody struct {
+	Message         string          `json:"message"`
+	ValidationError json.RawMessage `json:"validationError"`
+}
+
+// newAPIError builds an APIError from an error response and its body. The
+// body is decoded as a One Liquidity error when it is JSON and kept as is
+// otherwise.
+func newAPIError(r *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode:  r.StatusCode,
+		ContentType: r.Header.Get("Content-Type"),
+		Body:        body,
+	}
+
+	for _, h := range requestIDHeaders {
+		if id := r.Header.Get(h); id != "" {
+			e.RequestID = id
+			break
+		}
+	}
+
+	var eb errorBody
+	if err := json.Unmarshal(body, &eb); err != nil {
+		return e
+	}
+
+	e.Message = eb.Message
+	e.ValidationIssues = decodeValidationIssues(eb.ValidationError)
+
+	return e
+}
+
+// decodeValidationIssues reads the validationError field, which is either a
+// list of issues or a single one. Anything else is ignored.
+func decodeValidationIssues(raw json.RawMessage) []ValidationIssue {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var issues []ValidationIssue
+	if err := json.Unmarshal(raw, &issues); err == nil {
+		return issues
+	}
+
+	var issue ValidationIssue
+	if err := json.Unmarshal(raw, &issue); err == nil && (issue.Code != "" || issue.Message != "") {
+		return []ValidationIssue{issue}
+	}
+
+	return nil
+}
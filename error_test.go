@@ -0,0 +1,232 @@
+package liquidity
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClient_APIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		want       *APIError
+		sentinel   error
+		isSentinel func(error) bool
+	}{
+		{
+			name:   "decodes validation issues",
+			status: 400,
+			header: http.Header{"X-Request-Id": []string{"req-123"}},
+			body: `{"message":"Validation error","validationError":[` +
+				`{"code":"invalid_type","expected":"string","received":"number","path":["floatCurrencies",0],"message":"Expected string, received number"}]}`,
+			want: &APIError{
+				StatusCode: 400,
+				RequestID:  "req-123",
+				Message:    "Validation error",
+				ValidationIssues: []ValidationIssue{{
+					Code:     "invalid_type",
+					Expected: "string",
+					Received: "number",
+					Path:     []string{"floatCurrencies", "0"},
+					Message:  "Expected string, received number",
+				}},
+			},
+		},
+		{
+			name:       "matches not found",
+			status:     404,
+			body:       `{"message":"Card not found"}`,
+			want:       &APIError{StatusCode: 404, Message: "Card not found"},
+			sentinel:   ErrNotFound,
+			isSentinel: IsNotFound,
+		},
+		{
+			name:       "matches unauthorized",
+			status:     401,
+			body:       `{"message":"Unauthorized"}`,
+			want:       &APIError{StatusCode: 401, Message: "Unauthorized"},
+			sentinel:   ErrUnauthorized,
+			isSentinel: IsUnauthorized,
+		},
+		{
+			name:       "matches insufficient funds",
+			status:     400,
+			body:       `{"message":"Insufficient float balance"}`,
+			want:       &APIError{StatusCode: 400, Message: "Insufficient float balance"},
+			sentinel:   ErrInsufficientFunds,
+			isSentinel: IsInsufficientFunds,
+		},
+		{
+			name:   "server errors are not insufficient funds",
+			status: 500,
+			body:   `{"message":"Insufficient capacity, try again"}`,
+			want:   &APIError{StatusCode: 500, Message: "Insufficient capacity, try again"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&MockHttpClient{
+				DoFunc: func(r *http.Request) (*http.Response, error) {
+					res := jsonResponse(tt.status, tt.body)
+					for k, v := range tt.header {
+						res.Header[k] = v
+					}
+					return res, nil
+				},
+			})
+
+			_, err := c.GetCard("aa174033-fe13-4c3a-90b3-f3485a0e9c86", "214103800064766")
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("GetCard() error = %v, want an *APIError", err)
+			}
+			tt.want.Body = []byte(tt.body)
+			if !reflect.DeepEqual(apiErr, tt.want) {
+				t.Errorf("GetCard() error = %#v, want %#v", apiErr, tt.want)
+			}
+			if tt.sentinel != nil {
+				if !errors.Is(err, tt.sentinel) || !tt.isSentinel(err) {
+					t.Errorf("GetCard() error = %v, want it to match %v", err, tt.sentinel)
+				}
+				if errors.Is(err, ErrNotFound) != (tt.sentinel == ErrNotFound) {
+					t.Errorf("GetCard() error = %v, unexpectedly matches ErrNotFound", err)
+				}
+			}
+			if got, want := errors.Is(err, ErrInsufficientFunds), tt.sentinel == ErrInsufficientFunds; got != want {
+				t.Errorf("errors.Is(%v, ErrInsufficientFunds) = %v, want %v", err, got, want)
+			}
+		})
+	}
+}
+
+func TestAPIError_Error(t *testing.T) {
+	e := &APIError{
+		StatusCode: 400,
+		RequestID:  "req-123",
+		Message:    "Validation error",
+		ValidationIssues: []ValidationIssue{
+			{Path: []string{"userId"}, Message: "Required"},
+		},
+	}
+
+	want := "liquidity: 400 Bad Request: Validation error; userId: Required (request req-123)"
+	if got := e.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestAPIError_AsDeprecatedError(t *testing.T) {
+	var err error = &APIError{
+		StatusCode: 400,
+		Message:    "Validation error",
+		Body:       []byte(`{"message":"Validation error","validationError":{"code":"custom","message":"Required"}}`),
+	}
+
+	var old Error
+	if !errors.As(err, &old) {
+		t.Fatalf("errors.As(%v, *Error) = false", err)
+	}
+
+	want := Error{Message: "Validation error", ValidationError: map[string]interface{}{"code": "custom", "message": "Required"}}
+	if !reflect.DeepEqual(old, want) {
+		t.Errorf("Error = %#v, want %#v", old, want)
+	}
+}
+
+func TestClient_NonJSONError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantError   string
+	}{
+		{
+			name:        "keeps the status of an HTML gateway page",
+			status:      502,
+			contentType: "text/html",
+			body:        "<html>\n  <body><h1>502 Bad Gateway</h1></body>\n</html>",
+			wantError:   "liquidity: 502 Bad Gateway: <html> <body><h1>502 Bad Gateway</h1></body> </html>",
+		},
+		{
+			name:        "redacts card data",
+			status:      500,
+			contentType: "application/json",
+			body:        `{"error":"declined", "cardNumber":"4111111111111111"}`,
+			wantError:   `liquidity: 500 Internal Server Error: {"cardNumber":"[REDACTED]","error":"declined"}`,
+		},
+		{
+			name:      "handles an empty body",
+			status:    401,
+			wantError: "liquidity: 401 Unauthorized",
+		},
+		{
+			name:        "truncates long bodies",
+			status:      500,
+			contentType: "text/plain",
+			body:        strings.Repeat("x", 500),
+			wantError:   "liquidity: 500 Internal Server Error: " + strings.Repeat("x", 200) + "...",
+		},
+		{
+			name:        "masks card numbers in text",
+			status:      400,
+			contentType: "text/plain; charset=utf-8",
+			body:        "card 4111 1111 1111 1111 declined, ref 12345",
+			wantError:   "liquidity: 400 Bad Request: card [REDACTED] declined, ref 12345",
+		},
+		{
+			name:        "describes other bodies by size",
+			status:      500,
+			contentType: "application/octet-stream",
+			body:        "\x00\x01binary",
+			wantError:   "liquidity: 500 Internal Server Error: [non-JSON body, 8 bytes]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(&MockHttpClient{
+				DoFunc: func(r *http.Request) (*http.Response, error) {
+					res := jsonResponse(tt.status, tt.body)
+					res.Header.Set("Content-Type", tt.contentType)
+					return res, nil
+				},
+			})
+			c.SetRetryPolicy(NoRetry)
+
+			_, err := c.GetCard("aa174033-fe13-4c3a-90b3-f3485a0e9c86", "214103800064766")
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("GetCard() error = %v, want an *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.ContentType != tt.contentType || apiErr.Message != "" {
+				t.Errorf("GetCard() error = %#v", apiErr)
+			}
+			if got := err.Error(); got != tt.wantError {
+				t.Errorf("Error() = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestClient_EmptySuccess(t *testing.T) {
+	c := newTestClient(&MockHttpClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusNoContent, ""), nil
+		},
+	})
+
+	got, err := c.Freeze("aa174033-fe13-4c3a-90b3-f3485a0e9c86")
+	if err != nil {
+		t.Fatalf("Freeze() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, Resp{}) {
+		t.Errorf("Freeze() got = %v, want an empty response", got)
+	}
+}
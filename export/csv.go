@@ -0,0 +1,40 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// WriteCSV writes the statement as CSV: a header row, one row per
+// transaction and, after a blank row, the opening and closing balances and
+// the totals per currency.
+func WriteCSV(w io.Writer, st *Statement, opts *Options) error {
+	cw := csv.NewWriter(w)
+	cols := opts.columns()
+
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.Header
+	}
+	cw.Write(header)
+
+	for _, t := range st.Transactions {
+		row := make([]string, len(cols))
+		for i, c := range cols {
+			row[i] = c.Value(t)
+		}
+		cw.Write(row)
+	}
+
+	cw.Write(nil)
+	cw.Write([]string{"Opening Balance", st.Opening.Amount(), st.Currency})
+	cw.Write([]string{"Closing Balance", st.Closing.Amount(), st.Currency})
+	for _, c := range st.currencies() {
+		tot := st.Totals[c]
+		cw.Write([]string{"Total Credits", tot.Credits.Amount(), c})
+		cw.Write([]string{"Total Debits", tot.Debits.Amount(), c})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
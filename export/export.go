@@ -0,0 +1,297 @@
+// Package export builds card statements from One Liquidity transactions and
+// writes them as CSV, OFX or JSON Lines.
+package export
+
+import (
+	"context"
+	er "errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// TransactionLister lists a card's transactions. *liquidity.Client satisfies
+// it.
+type TransactionLister interface {
+	ListAllTransactions(ctx context.Context, cardId string, p liquidity.Params, maxItems int) ([]liquidity.D4, error)
+}
+
+// Statement is a card's transactions over [From, To) with the balances
+// around them.
+type Statement struct {
+	CardID   string
+	Currency string
+	From, To time.Time
+
+	// Opening is the balance before the first transaction in the period and
+	// Closing the balance after the last.
+	Opening liquidity.Money
+	Closing liquidity.Money
+
+	// Transactions are in time order.
+	Transactions []liquidity.D4
+	// Totals are keyed by currency.
+	Totals map[string]Totals
+}
+
+// Totals sums a statement's transactions in one currency.
+type Totals struct {
+	Credits liquidity.Money
+	Debits  liquidity.Money
+	Count   int
+}
+
+// Net returns credits minus debits.
+func (t Totals) Net() liquidity.Money {
+	net, _ := t.Credits.Sub(t.Debits)
+	return net
+}
+
+// Build fetches the transactions of a card in [from, to) and builds its
+// statement. The opening balance is the balance before the first transaction
+// in the period; only when the period has none is the card's earlier history
+// searched, back to maxLookback before from, for the last transaction before
+// it.
+func Build(ctx context.Context, src TransactionLister, cardID string, from, to time.Time) (*Statement, error) {
+	txns, err := src.ListAllTransactions(ctx, cardID, liquidity.Params{
+		StartDate: from.UTC().Format("2006-01-02"),
+		EndDate:   to.UTC().Format("2006-01-02"),
+	}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("export: listing transactions of card %s: %w", cardID, err)
+	}
+
+	st, err := NewStatement(cardID, txns, from, to)
+	if err != nil || len(st.Transactions) > 0 {
+		return st, err
+	}
+
+	day := from.UTC().Truncate(24 * time.Hour)
+	oldest := day.AddDate(-maxLookback, 0, 0)
+
+	// Walk back in windows that double in length, so only the most recent
+	// part of a long history is listed.
+	for days := 7; !day.Before(oldest); days *= 2 {
+		start := day.AddDate(0, 0, 1-days)
+		earlier, err := lastWindow(ctx, src, cardID, start, day, from)
+		if err != nil {
+			return nil, fmt.Errorf("export: listing transactions of card %s: %w", cardID, err)
+		}
+		if len(earlier) > 0 {
+			return NewStatement(cardID, append(earlier, txns...), from, to)
+		}
+
+		day = start.AddDate(0, 0, -1)
+	}
+
+	return st, nil
+}
+
+// maxLookback is how many years before a statement Build searches for its
+// opening balance when the period has no transactions.
+const maxLookback = 20
+
+// lastWindow returns the transactions before the given time in the days
+// first through last. A window with more than liquidity.DefaultMaxItems
+// transactions is split in two, trying the later half first.
+func lastWindow(ctx context.Context, src TransactionLister, cardID string, first, last, before time.Time) ([]liquidity.D4, error) {
+	txns, err := src.ListAllTransactions(ctx, cardID, liquidity.Params{
+		StartDate: first.Format("2006-01-02"),
+		EndDate:   last.Format("2006-01-02"),
+	}, 0)
+
+	if er.Is(err, liquidity.ErrMaxItemsExceeded) && first.Before(last) {
+		mid := first.AddDate(0, 0, int(last.Sub(first).Hours()/24)/2)
+
+		later, err := lastWindow(ctx, src, cardID, mid.AddDate(0, 0, 1), last, before)
+		if err != nil || len(later) > 0 {
+			return later, err
+		}
+		return lastWindow(ctx, src, cardID, first, mid, before)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	n := 0
+	for _, t := range txns {
+		if at, err := time.Parse(time.RFC3339, t.CreatedAt); err != nil || at.Before(before) {
+			txns[n] = t
+			n++
+		}
+	}
+
+	return txns[:n], nil
+}
+
+// NewStatement builds a statement for [from, to) from transactions already
+// fetched, which must include the last one before from for the opening
+// balance to be right.
+func NewStatement(cardID string, txns []liquidity.D4, from, to time.Time) (*Statement, error) {
+	type dated struct {
+		liquidity.D4
+		at time.Time
+	}
+
+	all := make([]dated, 0, len(txns))
+	for _, t := range txns {
+		at, err := time.Parse(time.RFC3339, t.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("export: transaction %s: invalid createdAt %q", t.TransactionId, t.CreatedAt)
+		}
+		all = append(all, dated{t, at})
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].at.Before(all[j].at) })
+
+	st := &Statement{CardID: cardID, From: from, To: to, Totals: make(map[string]Totals)}
+
+	for _, t := range all {
+		if st.Currency == "" {
+			st.Currency = currencyOf(t.D4)
+		}
+
+		switch {
+		case t.at.Before(from):
+			st.Opening = t.CardBalanceAfter
+			continue
+		case !t.at.Before(to):
+			continue
+		}
+
+		if len(st.Transactions) == 0 {
+			st.Opening = t.TransactionBalanceBefore
+		}
+		st.Transactions = append(st.Transactions, t.D4)
+
+		currency := currencyOf(t.D4)
+		tot := st.Totals[currency]
+		tot.Count++
+
+		var err error
+		if isDebit(t.D4) {
+			tot.Debits, err = tot.Debits.Add(t.Amount)
+		} else {
+			tot.Credits, err = tot.Credits.Add(t.Amount)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("export: transaction %s: %w", t.TransactionId, err)
+		}
+
+		st.Totals[currency] = tot
+	}
+
+	if st.Opening.Currency == "" {
+		st.Opening.Currency = st.Currency
+	}
+
+	st.Closing = st.Opening
+	if n := len(st.Transactions); n > 0 {
+		st.Closing = st.Transactions[n-1].CardBalanceAfter
+	}
+
+	return st, nil
+}
+
+// currencies returns the currencies of the totals in order.
+func (st *Statement) currencies() []string {
+	list := make([]string, 0, len(st.Totals))
+	for c := range st.Totals {
+		list = append(list, c)
+	}
+	sort.Strings(list)
+
+	return list
+}
+
+// Column is one column of a CSV or JSON Lines statement.
+type Column struct {
+	Header string
+	Value  func(t liquidity.D4) string
+}
+
+// fields are the transaction values available to Field.
+var fields = map[string]func(t liquidity.D4) string{
+	"date":          func(t liquidity.D4) string { return t.CreatedAt },
+	"id":            func(t liquidity.D4) string { return t.TransactionId },
+	"card":          func(t liquidity.D4) string { return t.CardId },
+	"type":          func(t liquidity.D4) string { return t.Type },
+	"narrative":     func(t liquidity.D4) string { return t.Narrative },
+	"amount":        func(t liquidity.D4) string { return t.Amount.Amount() },
+	"signedAmount":  func(t liquidity.D4) string { return signed(t).Amount() },
+	"currency":      currencyOf,
+	"balanceBefore": func(t liquidity.D4) string { return t.TransactionBalanceBefore.Amount() },
+	"balanceAfter":  func(t liquidity.D4) string { return t.CardBalanceAfter.Amount() },
+	"error":         func(t liquidity.D4) string { return t.ErrorDescription },
+}
+
+// Field returns a column showing one transaction value under header. The
+// fields are date, id, card, type, narrative, amount, signedAmount (negative
+// for debits), currency, balanceBefore, balanceAfter and error.
+func Field(field, header string) (Column, error) {
+	fn, ok := fields[field]
+	if !ok {
+		return Column{}, fmt.Errorf("export: unknown field %q", field)
+	}
+
+	return Column{Header: header, Value: fn}, nil
+}
+
+func mustField(field, header string) Column {
+	c, err := Field(field, header)
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// DefaultColumns are used when Options.Columns is empty.
+var DefaultColumns = []Column{
+	mustField("date", "Date"),
+	mustField("id", "Transaction ID"),
+	mustField("type", "Type"),
+	mustField("narrative", "Narrative"),
+	mustField("signedAmount", "Amount"),
+	mustField("currency", "Currency"),
+	mustField("balanceBefore", "Balance Before"),
+	mustField("balanceAfter", "Balance After"),
+}
+
+// Options configures the CSV and JSON Lines writers.
+type Options struct {
+	// Columns selects, orders and names the columns. Empty means
+	// DefaultColumns.
+	Columns []Column
+}
+
+func (o *Options) columns() []Column {
+	if o == nil || len(o.Columns) == 0 {
+		return DefaultColumns
+	}
+
+	return o.Columns
+}
+
+func isDebit(t liquidity.D4) bool {
+	return strings.EqualFold(t.Type, "debit")
+}
+
+// signed returns the amount, negative for debits.
+func signed(t liquidity.D4) liquidity.Money {
+	if isDebit(t) {
+		return t.Amount.Neg()
+	}
+
+	return t.Amount
+}
+
+func currencyOf(t liquidity.D4) string {
+	if t.Amount.Currency != "" {
+		return strings.ToUpper(t.Amount.Currency)
+	}
+
+	return strings.ToUpper(t.Currency)
+}
@@ -0,0 +1,235 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+	"github.com/bushaHQ/one-liquidity-go/liquiditytest"
+)
+
+var march = time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func usd(s string) liquidity.Money {
+	return liquidity.MustParseMoney(s, "USD")
+}
+
+func txn(id, kind, amount, before, after string, at time.Time) liquidity.D4 {
+	return liquidity.D4{
+		TransactionId:            id,
+		CardId:                   "card-1",
+		Type:                     kind,
+		Amount:                   usd(amount),
+		TransactionBalanceBefore: usd(before),
+		CardBalanceAfter:         usd(after),
+		Currency:                 "USD",
+		Narrative:                "Card " + kind,
+		CreatedAt:                at.Format(time.RFC3339),
+	}
+}
+
+func statement(t *testing.T) *Statement {
+	t.Helper()
+
+	txns := []liquidity.D4{
+		txn("t3", "debit", "2.25", "15", "12.75", march.Add(48*time.Hour)),
+		txn("t1", "credit", "10", "0", "10", march.Add(-time.Hour)),
+		txn("t2", "credit", "5", "10", "15", march.Add(time.Hour)),
+		txn("t4", "credit", "1", "12.75", "13.75", march.AddDate(0, 1, 0)),
+	}
+
+	st, err := NewStatement("card-1", txns, march, march.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("NewStatement() error = %v", err)
+	}
+
+	return st
+}
+
+func TestNewStatement(t *testing.T) {
+	st := statement(t)
+
+	if len(st.Transactions) != 2 || st.Transactions[0].TransactionId != "t2" {
+		t.Fatalf("Transactions = %+v, want t2 and t3", st.Transactions)
+	}
+	if !st.Opening.Equal(usd("10")) || !st.Closing.Equal(usd("12.75")) {
+		t.Errorf("balances = %v to %v, want 10.00 USD to 12.75 USD", st.Opening, st.Closing)
+	}
+
+	tot := st.Totals["USD"]
+	if !tot.Credits.Equal(usd("5")) || !tot.Debits.Equal(usd("2.25")) || tot.Count != 2 || !tot.Net().Equal(usd("2.75")) {
+		t.Errorf("Totals = %+v", tot)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	date, _ := Field("date", "When")
+	amount, _ := Field("signedAmount", "Amount")
+
+	var b bytes.Buffer
+	if err := WriteCSV(&b, statement(t), &Options{Columns: []Column{date, amount}}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `When,Amount
+2023-03-01T01:00:00Z,5.00
+2023-03-03T00:00:00Z,-2.25
+
+Opening Balance,10.00,USD
+Closing Balance,12.75,USD
+Total Credits,5.00,USD
+Total Debits,2.25,USD
+`
+	if b.String() != want {
+		t.Errorf("WriteCSV() =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	if _, err := Field("pan", "PAN"); err == nil {
+		t.Error("Field(pan) succeeded")
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteJSONL(&b, statement(t), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []map[string]interface{}
+	sc := bufio.NewScanner(&b)
+	for sc.Scan() {
+		var rec map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		records = append(records, rec)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+	if records[1]["Amount"] != "-2.25" || records[1]["Transaction ID"] != "t3" {
+		t.Errorf("records[1] = %v", records[1])
+	}
+	if records[2]["record"] != "summary" || records[2]["closingBalance"] != "12.75" {
+		t.Errorf("summary = %v", records[2])
+	}
+}
+
+func TestWriteOFX(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteOFX(&b, statement(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc ofxDoc
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteOFX() wrote invalid XML: %v\n%s", err, b.String())
+	}
+
+	rs := doc.Statement.Rs
+	if rs.CurDef != "USD" || rs.LedgerBal.BalAmt != "12.75" || len(rs.TranList.Trns) != 2 {
+		t.Fatalf("statement = %+v", rs)
+	}
+	if got := rs.TranList.Trns[1]; got.TrnType != "DEBIT" || got.TrnAmt != "-2.25" || got.DTPosted != "20230303000000" {
+		t.Errorf("STMTTRN = %+v", got)
+	}
+	if !strings.Contains(b.String(), `OFXHEADER="200"`) {
+		t.Error("missing OFX header")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	srv := liquiditytest.NewServer()
+	defer srv.Close()
+
+	now := march.Add(-time.Hour)
+	srv.SetClock(func() time.Time { return now })
+	srv.SetFloat(usd("100"))
+
+	cl := srv.Client()
+	userID := srv.AddUser(liquidity.CreateUserData{FirstName: "Ada", UID: "u-1"})
+	card, err := cl.CreateCard(liquidity.CreateCardData{UserId: userID, Expiry: "2099-01-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cardID := card.Data.CardId
+
+	for _, amount := range []string{"20", "7", "3"} {
+		if _, err := cl.TopUp(cardID, usd(amount)); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(24 * time.Hour)
+	}
+
+	st, err := Build(context.Background(), cl, cardID, march, march.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if len(st.Transactions) != 2 || !st.Opening.Equal(usd("20")) || !st.Closing.Equal(usd("30")) {
+		t.Errorf("Build() = %d transactions, %v to %v; want 2, 20.00 USD to 30.00 USD", len(st.Transactions), st.Opening, st.Closing)
+	}
+	if n := srv.Calls("GET", "/card/v1/transactions"); n != 1 {
+		t.Errorf("Build() listed transactions %d times, want only the period", n)
+	}
+
+	st, err = Build(context.Background(), cl, cardID, march.AddDate(0, 1, 0), march.AddDate(0, 2, 0))
+	if err != nil {
+		t.Fatalf("Build() of an empty period error = %v", err)
+	}
+	if len(st.Transactions) != 0 || !st.Opening.Equal(usd("30")) || !st.Closing.Equal(usd("30")) {
+		t.Errorf("Build() of an empty period = %d transactions, %v to %v; want 0, 30.00 USD to 30.00 USD", len(st.Transactions), st.Opening, st.Closing)
+	}
+}
+
+// history is a TransactionLister over a fixed list of transactions that
+// keeps to the ListAll helpers' item cap.
+type history struct {
+	txns []liquidity.D4
+}
+
+func (h *history) ListAllTransactions(ctx context.Context, cardId string, p liquidity.Params, maxItems int) ([]liquidity.D4, error) {
+	start, _ := time.Parse("2006-01-02", p.StartDate)
+	end, _ := time.Parse("2006-01-02", p.EndDate)
+
+	var txns []liquidity.D4
+	for _, t := range h.txns {
+		at, _ := time.Parse(time.RFC3339, t.CreatedAt)
+		if at.Before(start) || !at.Before(end.AddDate(0, 0, 1)) {
+			continue
+		}
+		if len(txns) == liquidity.DefaultMaxItems {
+			return txns, liquidity.ErrMaxItemsExceeded
+		}
+		txns = append(txns, t)
+	}
+
+	return txns, nil
+}
+
+func TestBuild_LongHistory(t *testing.T) {
+	h := &history{}
+	at := march.Add(-time.Hour)
+	for i := 1; i <= liquidity.DefaultMaxItems+50; i++ {
+		balance := fmt.Sprint(i)
+		h.txns = append(h.txns, txn(fmt.Sprint("t", i), "credit", "1", balance, balance+".5", at))
+		at = at.Add(-time.Minute)
+	}
+
+	st, err := Build(context.Background(), h, "card-1", march.AddDate(0, 0, 3), march.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if len(st.Transactions) != 0 || !st.Opening.Equal(usd("1.5")) || !st.Closing.Equal(usd("1.5")) {
+		t.Errorf("Build() = %d transactions, %v to %v; want 0, 1.50 USD to 1.50 USD", len(st.Transactions), st.Opening, st.Closing)
+	}
+}
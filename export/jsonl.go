@@ -0,0 +1,66 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// WriteJSONL writes the statement as JSON Lines: one object per transaction
+// with the configured columns as keys, in order, followed by a summary
+// object. Each object has a "record" key of "transaction" or "summary".
+func WriteJSONL(w io.Writer, st *Statement, opts *Options) error {
+	cols := opts.columns()
+
+	for _, t := range st.Transactions {
+		var b bytes.Buffer
+		b.WriteString(`{"record":"transaction"`)
+		for _, c := range cols {
+			k, _ := json.Marshal(c.Header)
+			v, _ := json.Marshal(c.Value(t))
+			b.WriteByte(',')
+			b.Write(k)
+			b.WriteByte(':')
+			b.Write(v)
+		}
+		b.WriteString("}\n")
+
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	type totals struct {
+		Credits string `json:"credits"`
+		Debits  string `json:"debits"`
+		Net     string `json:"net"`
+		Count   int    `json:"count"`
+	}
+
+	summary := struct {
+		Record   string            `json:"record"`
+		CardID   string            `json:"cardId"`
+		Currency string            `json:"currency"`
+		From     time.Time         `json:"from"`
+		To       time.Time         `json:"to"`
+		Opening  string            `json:"openingBalance"`
+		Closing  string            `json:"closingBalance"`
+		Totals   map[string]totals `json:"totals"`
+	}{
+		Record:   "summary",
+		CardID:   st.CardID,
+		Currency: st.Currency,
+		From:     st.From,
+		To:       st.To,
+		Opening:  st.Opening.Amount(),
+		Closing:  st.Closing.Amount(),
+		Totals:   make(map[string]totals),
+	}
+
+	for c, tot := range st.Totals {
+		summary.Totals[c] = totals{tot.Credits.Amount(), tot.Debits.Amount(), tot.Net().Amount(), tot.Count}
+	}
+
+	return json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,114 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// ofxTime formats times as OFX dates.
+const ofxTime = "20060102150405"
+
+type ofxDoc struct {
+	XMLName xml.Name `xml:"OFX"`
+	Signon  struct {
+		Status   ofxStatus `xml:"SONRS>STATUS"`
+		DTServer string    `xml:"SONRS>DTSERVER"`
+		Language string    `xml:"SONRS>LANGUAGE"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Statement struct {
+		TrnUID string    `xml:"TRNUID"`
+		Status ofxStatus `xml:"STATUS"`
+		Rs     ofxStmtRs `xml:"CCSTMTRS"`
+	} `xml:"CREDITCARDMSGSRSV1>CCSTMTTRNRS"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxStmtRs struct {
+	CurDef   string `xml:"CURDEF"`
+	AcctID   string `xml:"CCACCTFROM>ACCTID"`
+	TranList struct {
+		DTStart string     `xml:"DTSTART"`
+		DTEnd   string     `xml:"DTEND"`
+		Trns    []ofxTrans `xml:"STMTTRN"`
+	} `xml:"BANKTRANLIST"`
+	LedgerBal struct {
+		BalAmt string `xml:"BALAMT"`
+		DTAsOf string `xml:"DTASOF"`
+	} `xml:"LEDGERBAL"`
+}
+
+type ofxTrans struct {
+	TrnType  string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	TrnAmt   string `xml:"TRNAMT"`
+	FITID    string `xml:"FITID"`
+	Name     string `xml:"NAME,omitempty"`
+	Memo     string `xml:"MEMO,omitempty"`
+}
+
+// WriteOFX writes the statement as an OFX 2 credit card statement, which
+// accounting software can import. The card ID is the account ID and the
+// closing balance the ledger balance.
+func WriteOFX(w io.Writer, st *Statement) error {
+	var doc ofxDoc
+
+	doc.Signon.DTServer = time.Now().UTC().Format(ofxTime)
+	doc.Signon.Language = "ENG"
+	doc.Signon.Status.Severity = "INFO"
+	doc.Statement.TrnUID = "0"
+	doc.Statement.Status.Severity = "INFO"
+
+	rs := &doc.Statement.Rs
+	rs.CurDef = st.Currency
+	rs.AcctID = st.CardID
+	rs.TranList.DTStart = st.From.UTC().Format(ofxTime)
+	rs.TranList.DTEnd = st.To.UTC().Format(ofxTime)
+	rs.LedgerBal.BalAmt = st.Closing.Amount()
+	rs.LedgerBal.DTAsOf = st.To.UTC().Format(ofxTime)
+
+	for _, t := range st.Transactions {
+		trn := ofxTrans{
+			TrnType: "CREDIT",
+			TrnAmt:  signed(t).Amount(),
+			FITID:   t.TransactionId,
+			Name:    truncate(t.Narrative, 32),
+			Memo:    t.Narrative,
+		}
+		if isDebit(t) {
+			trn.TrnType = "DEBIT"
+		}
+		if at, err := time.Parse(time.RFC3339, t.CreatedAt); err == nil {
+			trn.DTPosted = at.UTC().Format(ofxTime)
+		}
+
+		rs.TranList.Trns = append(rs.TranList.Trns, trn)
+	}
+
+	if _, err := io.WriteString(w, xml.Header+`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`+"\n"); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// truncate shortens s to n runes, as OFX limits NAME to 32 characters.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n])
+}
@@ -0,0 +1,173 @@
+package liquidity
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	er "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyHeader     = "Idempotency-Key"
+	defaultIdempotencyTTL = 24 * time.Hour
+	// idempotencySweepInterval is the least time between two sweeps of
+	// expired entries in a MemoryIdempotencyStore.
+	idempotencySweepInterval = time.Minute
+)
+
+// IdempotencyStore keeps the responses of idempotent calls so that a call
+// repeated with the same key returns the first result instead of being sent
+// again. Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, body []byte)
+}
+
+type idempotencyEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore. Entries expire
+// after the TTL it was created with.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]idempotencyEntry
+	swept   time.Time
+}
+
+// NewMemoryIdempotencyStore creates an in-memory store whose entries are kept
+// for ttl. A ttl of zero keeps entries forever.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		entries: make(map[string]idempotencyEntry),
+	}
+}
+
+// Get returns the stored response for key, if any.
+func (s *MemoryIdempotencyStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(s.entries, key)
+		return nil, false
+	}
+
+	return e.body, true
+}
+
+// Set stores the response for key.
+func (s *MemoryIdempotencyStore) Set(key string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.ttl > 0 && now.Sub(s.swept) >= idempotencySweepInterval {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.swept = now
+	}
+
+	e := idempotencyEntry{body: body}
+	if s.ttl > 0 {
+		e.expires = now.Add(s.ttl)
+	}
+	s.entries[key] = e
+}
+
+// ErrIdempotencyKeyReused is returned when an idempotency key is sent again
+// with a different request than the one it was first used for.
+var ErrIdempotencyKeyReused = er.New("liquidity: idempotency key reused for a different request")
+
+// CallOption configures a single money-moving call.
+type CallOption func(o *callOptions)
+
+type callOptions struct {
+	idempotencyKey string
+}
+
+// IdempotencyKey makes a money-moving call (CreateCard, TopUp, Debit,
+// PostIntegratorDeposit) send key as its Idempotency-Key. Repeating the same
+// request with the same key returns the stored result rather than sending it
+// twice. The key applies to that call only.
+func IdempotencyKey(key string) CallOption {
+	return func(o *callOptions) { o.idempotencyKey = key }
+}
+
+// IdempotencyKeyOf returns the key set by IdempotencyKey among opts, or ""
+// when there is none. It lets wrappers of the client recognise a retry.
+func IdempotencyKeyOf(opts ...CallOption) string {
+	return newCallOptions(opts).idempotencyKey
+}
+
+func newCallOptions(opts []CallOption) callOptions {
+	var o callOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// NewIdempotencyKey generates a random idempotency key.
+func NewIdempotencyKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("liquidity: unable to generate idempotency key: " + err.Error())
+	}
+
+	// format as a version 4 UUID
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// fingerprint identifies the request an idempotency key was used for.
+func fingerprint(req *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.Path+"\n")
+
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			io.Copy(h, body)
+			body.Close()
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// storedResponse prefixes a response body with the fingerprint of its
+// request.
+func storedResponse(fp string, body []byte) []byte {
+	return append([]byte(fp+"\n"), body...)
+}
+
+// replay returns the stored response body if it was stored for the request
+// with fingerprint fp.
+func replay(stored []byte, fp string) ([]byte, error) {
+	prefix := []byte(fp + "\n")
+	if !bytes.HasPrefix(stored, prefix) {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	return stored[len(prefix):], nil
+}
@@ -1,9 +1,6 @@
 package liquidity
 
-import (
-	"fmt"
-	"strings"
-)
+import "context"
 
 // url paths to the various endpoints
 const (
@@ -61,164 +58,282 @@ type UpdateUserAddressData struct {
 
 // RegisterIntegrator allows an integrator register with the system
 func (cl *Client) RegisterIntegrator(data RegisterIntegratorData) (IntegratorResp, error) {
+	return cl.RegisterIntegratorContext(context.Background(), data)
+}
+
+// RegisterIntegratorContext is like RegisterIntegrator but uses ctx for the request
+func (cl *Client) RegisterIntegratorContext(ctx context.Context, data RegisterIntegratorData) (IntegratorResp, error) {
 	var res IntegratorResp
-	err := cl.post("/integrator/v1/register", data, &res)
+	err := cl.post(ctx, "/integrator/v1/register", data, &res)
 	return res, err
 }
 
 // UpdateWebhook allows an integrator to update their webhook URL
 func (cl *Client) UpdateWebhook(webhook string) (Resp, error) {
+	return cl.UpdateWebhookContext(context.Background(), webhook)
+}
+
+// UpdateWebhookContext is like UpdateWebhook but uses ctx for the request
+func (cl *Client) UpdateWebhookContext(ctx context.Context, webhook string) (Resp, error) {
 	var res Resp
-	err := cl.patch("/integrator/v1/webhook", w{webhook}, &res)
+	err := cl.patch(ctx, "/integrator/v1/webhook", w{webhook}, &res)
 	return res, err
 }
 
-//CreateCard allows an integrator to create a virtual card for their user
-func (cl *Client) CreateCard(data CreateCardData) (CardResp, error) {
+//CreateCard allows an integrator to create a virtual card for their user. See
+// IdempotencyKey for safely repeating the call
+func (cl *Client) CreateCard(data CreateCardData, opts ...CallOption) (CardResp, error) {
+	return cl.CreateCardContext(context.Background(), data, opts...)
+}
+
+// CreateCardContext is like CreateCard but uses ctx for the request
+func (cl *Client) CreateCardContext(ctx context.Context, data CreateCardData, opts ...CallOption) (CardResp, error) {
 	var res CardResp
-	err := cl.post("/card/v1", data, &res)
+	err := cl.post(ctx, "/card/v1", data, &res, opts...)
 	return res, err
 }
 
 // GetCard allows an integrator to get full details of one card for their user
 func (cl *Client) GetCard(card string, trackingNumber string) (CardResp, error) {
+	return cl.GetCardContext(context.Background(), card, trackingNumber)
+}
+
+// GetCardContext is like GetCard but uses ctx for the request
+func (cl *Client) GetCardContext(ctx context.Context, card string, trackingNumber string) (CardResp, error) {
 	var res CardResp
-	err := cl.get(fmt.Sprintf("/card/v1?card=%s&trackingNumber=%s", card, trackingNumber), nil, &res)
+	err := cl.get(ctx, "/card/v1", cardQuery{card, trackingNumber}, &res)
 	return res, err
 }
 
 // GetCards allows an integrator to get all cards for their user
 func (cl *Client) GetCards(p Params) (CardsResp, error) {
+	return cl.GetCardsContext(context.Background(), p)
+}
+
+// GetCardsContext is like GetCards but uses ctx for the request
+func (cl *Client) GetCardsContext(ctx context.Context, p Params) (CardsResp, error) {
 	var res CardsResp
-	err := cl.get(fmt.Sprintf("/cards/v1?user=%s&type=%s&startDate=%s&endDate=%s&limit=%d&lek=%s", p.Id, p.Type, p.StartDate, p.EndDate, p.Limit, p.Lek), nil, &res)
+	err := cl.get(ctx, "/cards/v1", cardsQuery{p.Id, p.Type, p.StartDate, p.EndDate, p.Limit, p.Lek}, &res)
 	return res, err
 }
 
-// TopUp allows an integrator to top up the card balance of a user
-func (cl *Client) TopUp(cardId string, amount float64) (CardResp, error) {
+// TopUp allows an integrator to top up the card balance of a user. See
+// IdempotencyKey for safely repeating the call
+func (cl *Client) TopUp(cardId string, amount Money, opts ...CallOption) (CardResp, error) {
+	return cl.TopUpContext(context.Background(), cardId, amount, opts...)
+}
+
+// TopUpContext is like TopUp but uses ctx for the request
+func (cl *Client) TopUpContext(ctx context.Context, cardId string, amount Money, opts ...CallOption) (CardResp, error) {
 	var res CardResp
-	err := cl.patch("/card/v1/credit/balance", t{cardId, amount}, &res)
+	if err := checkAmount(amount, false); err != nil {
+		return res, err
+	}
+
+	err := cl.patch(ctx, "/card/v1/credit/balance", t{cardId, amount}, &res, opts...)
 	return res, err
 }
 
-// Debit allows an integrator to deduct from the card balance of a user
-func (cl *Client) Debit(cardId string, amount float64) (CardResp, error) {
+// Debit allows an integrator to deduct from the card balance of a user. See
+// IdempotencyKey for safely repeating the call
+func (cl *Client) Debit(cardId string, amount Money, opts ...CallOption) (CardResp, error) {
+	return cl.DebitContext(context.Background(), cardId, amount, opts...)
+}
+
+// DebitContext is like Debit but uses ctx for the request
+func (cl *Client) DebitContext(ctx context.Context, cardId string, amount Money, opts ...CallOption) (CardResp, error) {
 	var res CardResp
-	err := cl.patch("/card/v1/debit/balance", t{cardId, amount}, &res)
+	if err := checkAmount(amount, false); err != nil {
+		return res, err
+	}
+
+	err := cl.patch(ctx, "/card/v1/debit/balance", t{cardId, amount}, &res, opts...)
 	return res, err
 }
 
 // Freeze allows an integrator or admin to freeze any type of card
 func (cl *Client) Freeze(cardId string) (Resp, error) {
+	return cl.FreezeContext(context.Background(), cardId)
+}
+
+// FreezeContext is like Freeze but uses ctx for the request
+func (cl *Client) FreezeContext(ctx context.Context, cardId string) (Resp, error) {
 	var res Resp
-	err := cl.patch("/card/v1/freeze", s{CardId: cardId}, &res)
+	err := cl.patch(ctx, "/card/v1/freeze", s{CardId: cardId}, &res)
 	return res, err
 }
 
 // Unfreeze allows an integrator or admin to unfreeze any type of card
 func (cl *Client) Unfreeze(cardId string) (Resp, error) {
+	return cl.UnfreezeContext(context.Background(), cardId)
+}
+
+// UnfreezeContext is like Unfreeze but uses ctx for the request
+func (cl *Client) UnfreezeContext(ctx context.Context, cardId string) (Resp, error) {
 	var res Resp
-	err := cl.patch("/card/v1/unfreeze", s{CardId: cardId}, &res)
+	err := cl.patch(ctx, "/card/v1/unfreeze", s{CardId: cardId}, &res)
 	return res, err
 }
 
 // StopCard allows an integrator to stop a card
 func (cl *Client) StopCard(cardId string, reasonId int) (Resp, error) {
+	return cl.StopCardContext(context.Background(), cardId, reasonId)
+}
+
+// StopCardContext is like StopCard but uses ctx for the request
+func (cl *Client) StopCardContext(ctx context.Context, cardId string, reasonId int) (Resp, error) {
 	var res Resp
-	err := cl.patch("/card/v1/stop", s{cardId, reasonId}, &res)
+	err := cl.patch(ctx, "/card/v1/stop", s{cardId, reasonId}, &res)
 	return res, err
 }
 
 // GetFailedTransaction returns the details of a failed transaction
 func (cl *Client) GetFailedTransaction(txnId string) (TransactionResp, error) {
+	return cl.GetFailedTransactionContext(context.Background(), txnId)
+}
+
+// GetFailedTransactionContext is like GetFailedTransaction but uses ctx for the request
+func (cl *Client) GetFailedTransactionContext(ctx context.Context, txnId string) (TransactionResp, error) {
 	var res TransactionResp
-	err := cl.get(fmt.Sprintf("/card/v1/transaction/failed?transaction=%s", txnId), nil, &res)
+	err := cl.get(ctx, "/card/v1/transaction/failed", transactionQuery{txnId}, &res)
 	return res, err
 }
 
 // GetFailedTransactions returns the details of all failed transactions
 func (cl *Client) GetFailedTransactions(p Params) (TransactionsResp, error) {
+	return cl.GetFailedTransactionsContext(context.Background(), p)
+}
+
+// GetFailedTransactionsContext is like GetFailedTransactions but uses ctx for the request
+func (cl *Client) GetFailedTransactionsContext(ctx context.Context, p Params) (TransactionsResp, error) {
 	var res TransactionsResp
-	err := cl.get(fmt.Sprintf("/card/v1/transactions/failed?card=%s&startDate=%s&endDate=%s&limit=%d&lek=%s", p.Id, p.StartDate, p.EndDate, p.Limit, p.Lek), nil, &res)
+	err := cl.get(ctx, "/card/v1/transactions/failed", transactionsQuery{p.Id, p.StartDate, p.EndDate, p.Limit, p.Lek}, &res)
 	return res, err
 }
 
 // GetTransaction allows integrators to get a list of all transactions for a given card
 func (cl *Client) GetTransaction(cardId string, p Params) (TransactionsResp, error) {
+	return cl.GetTransactionContext(context.Background(), cardId, p)
+}
+
+// GetTransactionContext is like GetTransaction but uses ctx for the request
+func (cl *Client) GetTransactionContext(ctx context.Context, cardId string, p Params) (TransactionsResp, error) {
 	var res TransactionsResp
-	err := cl.get(fmt.Sprintf("/card/v1/transactions?card=%s&startDate=%s&endDate=%s&limit=%d&lek=%s", cardId, p.StartDate, p.EndDate, p.Limit, p.Lek), nil, &res)
+	err := cl.get(ctx, "/card/v1/transactions", transactionsQuery{cardId, p.StartDate, p.EndDate, p.Limit, p.Lek}, &res)
 	return res, err
 }
 
 // GetIntegratorDeposit allows an integrator retrieve a deposit
 func (cl *Client) GetIntegratorDeposit(depositId string) (DepositResp, error) {
+	return cl.GetIntegratorDepositContext(context.Background(), depositId)
+}
+
+// GetIntegratorDepositContext is like GetIntegratorDeposit but uses ctx for the request
+func (cl *Client) GetIntegratorDepositContext(ctx context.Context, depositId string) (DepositResp, error) {
 	var res DepositResp
-	err := cl.get(fmt.Sprintf("/integrator/v1/deposit?deposit=%s", depositId), nil, &res)
+	err := cl.get(ctx, "/integrator/v1/deposit", depositQuery{depositId}, &res)
 	return res, err
 }
 
-// PostIntegratorDeposit allows an admin to update an integrator's deposit
-func (cl *Client) PostIntegratorDeposit(amount int, currency string) (PostDepositResp, error) {
+// PostIntegratorDeposit allows an admin to update an integrator's deposit. The
+// deposit currency is the currency of amount. See IdempotencyKey for safely
+// repeating the call
+func (cl *Client) PostIntegratorDeposit(amount Money, opts ...CallOption) (PostDepositResp, error) {
+	return cl.PostIntegratorDepositContext(context.Background(), amount, opts...)
+}
+
+// PostIntegratorDepositContext is like PostIntegratorDeposit but uses ctx for the
+// request
+func (cl *Client) PostIntegratorDepositContext(ctx context.Context, amount Money, opts ...CallOption) (PostDepositResp, error) {
 	var res PostDepositResp
-	err := cl.post("/integrator/v1/deposit", d{amount, currency}, &res)
+	if err := checkAmount(amount, true); err != nil {
+		return res, err
+	}
+
+	err := cl.post(ctx, "/integrator/v1/deposit", d{amount, amount.Currency}, &res, opts...)
 	return res, err
 }
 
 // GetIntegratorFloats retrieves an integrators list of float account balances for given array of currencies
 func (cl *Client) GetIntegratorFloats(currencies []string) (FloatsResp, error) {
-	var res FloatsResp
-	bd := strings.Builder{}
-	for idx, currency := range currencies {
-
-		if idx == len(currencies)-1 {
-			bd.WriteString("currencies=" + currency)
-			break
-		}
+	return cl.GetIntegratorFloatsContext(context.Background(), currencies)
+}
 
-		bd.WriteString("currencies=" + currency + "&")
-	}
-	err := cl.get(fmt.Sprintf("/integrator/v1/floats?%s", bd.String()), nil, &res)
+// GetIntegratorFloatsContext is like GetIntegratorFloats but uses ctx for the request
+func (cl *Client) GetIntegratorFloatsContext(ctx context.Context, currencies []string) (FloatsResp, error) {
+	var res FloatsResp
+	err := cl.get(ctx, "/integrator/v1/floats", floatsQuery{currencies}, &res)
 	return res, err
 }
 
 // GetIntegratorFloat retrieves an integrator's float account balance for a given currency
 func (cl *Client) GetIntegratorFloat(currency string) (FloatResp, error) {
+	return cl.GetIntegratorFloatContext(context.Background(), currency)
+}
+
+// GetIntegratorFloatContext is like GetIntegratorFloat but uses ctx for the request
+func (cl *Client) GetIntegratorFloatContext(ctx context.Context, currency string) (FloatResp, error) {
 	var res FloatResp
-	err := cl.get(fmt.Sprintf("/integrator/v1/float?currency=%s", currency), nil, &res)
+	err := cl.get(ctx, "/integrator/v1/float", floatQuery{currency}, &res)
 	return res, err
 }
 
 // UpdateFloatDefault allows an integrator to update their default float
 func (cl *Client) UpdateFloatDefault(floatId string) (Resp, error) {
+	return cl.UpdateFloatDefaultContext(context.Background(), floatId)
+}
+
+// UpdateFloatDefaultContext is like UpdateFloatDefault but uses ctx for the request
+func (cl *Client) UpdateFloatDefaultContext(ctx context.Context, floatId string) (Resp, error) {
 	var res Resp
-	err := cl.patch("/integrator/v1/float/default", f{floatId}, &res)
+	err := cl.patch(ctx, "/integrator/v1/float/default", f{floatId}, &res)
 	return res, err
 }
 
 // GetUser Users allows an integrator to create a user
-func (cl *Client) GetUser(userID string) (getUserResp, error) {
-	var res getUserResp
-	err := cl.get(fmt.Sprintf("%s?userId=%s", userEndpoint, userID), nil, &res)
+func (cl *Client) GetUser(userID string) (UserResp, error) {
+	return cl.GetUserContext(context.Background(), userID)
+}
+
+// GetUserContext is like GetUser but uses ctx for the request
+func (cl *Client) GetUserContext(ctx context.Context, userID string) (UserResp, error) {
+	var res UserResp
+	err := cl.get(ctx, userEndpoint, userQuery{userID}, &res)
 	return res, err
 }
 
 // CreateUser Users allows an integrator to create a user
-func (cl *Client) CreateUser(userData CreateUserData) (createUserResp, error) {
-	var res createUserResp
-	err := cl.post(userEndpoint, userData, &res)
+func (cl *Client) CreateUser(userData CreateUserData) (CreateUserResp, error) {
+	return cl.CreateUserContext(context.Background(), userData)
+}
+
+// CreateUserContext is like CreateUser but uses ctx for the request
+func (cl *Client) CreateUserContext(ctx context.Context, userData CreateUserData) (CreateUserResp, error) {
+	var res CreateUserResp
+	err := cl.post(ctx, userEndpoint, userData, &res)
 	return res, err
 }
 
 // UpdateUserAdress allows an integrator to update address, postal code and KYC country
-func (cl *Client) UpdateUserAddress(updateData UpdateUserAddressData) (updateUserAddressResp, error) {
-	var res updateUserAddressResp
-	err := cl.patch(getUserAddress, updateData, &res)
+func (cl *Client) UpdateUserAddress(updateData UpdateUserAddressData) (UpdateUserAddressResp, error) {
+	return cl.UpdateUserAddressContext(context.Background(), updateData)
+}
+
+// UpdateUserAddressContext is like UpdateUserAddress but uses ctx for the request
+func (cl *Client) UpdateUserAddressContext(ctx context.Context, updateData UpdateUserAddressData) (UpdateUserAddressResp, error) {
+	var res UpdateUserAddressResp
+	err := cl.patch(ctx, getUserAddress, updateData, &res)
 	return res, err
 }
 
 // GetCardUserDocURL allows an integrator to update address, postal code and KYC country
-func (cl *Client) GetCardUserDocURL(userID string) (getCardUserDocURLResp, error) {
-	var res getCardUserDocURLResp
-	err := cl.get(fmt.Sprintf("%s?user=%s", getUserDoc, userID), nil, &res)
+func (cl *Client) GetCardUserDocURL(userID string) (CardUserDocURLResp, error) {
+	return cl.GetCardUserDocURLContext(context.Background(), userID)
+}
+
+// GetCardUserDocURLContext is like GetCardUserDocURL but uses ctx for the request
+func (cl *Client) GetCardUserDocURLContext(ctx context.Context, userID string) (CardUserDocURLResp, error) {
+	var res CardUserDocURLResp
+	err := cl.get(ctx, getUserDoc, userDocQuery{userID}, &res)
 	return res, err
 }
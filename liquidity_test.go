@@ -2,7 +2,9 @@ package liquidity
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -227,7 +229,7 @@ func TestClient_CreateCard(t *testing.T) {
 					CardNumber:     "5368989511270083",
 					Last4:          "0083",
 					TrackingNumber: "147203800064758",
-					Balance:        0,
+					Balance:        Money{Currency: "USD"},
 					Currency:       "USD",
 					SingleUse:      false,
 					CardName:       "Sofiyu Soft",
@@ -319,7 +321,7 @@ func TestClient_GetCard(t *testing.T) {
 					CardNumber:     "5368989511270083",
 					Last4:          "0083",
 					TrackingNumber: "147203800064758",
-					Balance:        0,
+					Balance:        Money{Currency: "USD"},
 					Status:         "issued",
 					Currency:       "USD",
 					SingleUse:      false,
@@ -412,7 +414,7 @@ func TestClient_GetCards(t *testing.T) {
 						Cvv2:           "372",
 						Last4:          "5761",
 						TrackingNumber: "986003800064765",
-						Balance:        0,
+						Balance:        Money{Currency: "USD"},
 						Status:         "issued",
 						Currency:       "USD",
 						SingleUse:      false,
@@ -443,7 +445,7 @@ func TestClient_TopUp(t *testing.T) {
 
 	type args struct {
 		cardId string
-		amount float64
+		amount Money
 	}
 	tests := []struct {
 		name           string
@@ -475,7 +477,7 @@ func TestClient_TopUp(t *testing.T) {
 			},
 			args: args{
 				cardId: "c954e4c9-8ca8-4d1d-8ebf-bb374e9f1b1d",
-				amount: 1000,
+				amount: MustParseMoney("1000", "USD"),
 			},
 			want: CardResp{
 				Message: "Ok",
@@ -502,7 +504,7 @@ func TestClient_Debit(t *testing.T) {
 
 	type args struct {
 		cardId string
-		amount float64
+		amount Money
 	}
 	tests := []struct {
 		name           string
@@ -534,7 +536,7 @@ func TestClient_Debit(t *testing.T) {
 			},
 			args: args{
 				cardId: "c954e4c9-8ca8-4d1d-8ebf-bb374e9f1b1d",
-				amount: 10,
+				amount: MustParseMoney("10", "USD"),
 			},
 			want: CardResp{
 				Message: "Ok",
@@ -601,7 +603,7 @@ func TestClient_Freeze(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			//cl.SetHTTPClient(&tt.mockHttpClient)
+			cl.SetHTTPClient(&tt.mockHttpClient)
 			got, err := cl.Freeze(tt.args.CardID)
 			if (err != nil) != tt.wantErr {
 				log.Println(err)
@@ -659,7 +661,7 @@ func TestClient_Unfreeze(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// cl.SetHTTPClient(&tt.mockHttpClient)
+			cl.SetHTTPClient(&tt.mockHttpClient)
 			got, err := cl.Unfreeze(tt.args.cardId)
 			if (err != nil) != tt.wantErr {
 				log.Println(err)
@@ -769,18 +771,18 @@ func TestClient_GetFailedTransaction(t *testing.T) {
 				txnId: "",
 			},
 			want: TransactionResp{
-				Message: "",
+				Message: "Ok",
 				Data: D4{
 					TransactionId:            "",
 					DebitId:                  "",
 					DebitCurrency:            "",
 					ConversionRate:           0,
 					CreditCurrency:           "",
-					TransactionBalanceBefore: 0,
-					CardBalanceAfter:         0,
+					TransactionBalanceBefore: Money{},
+					CardBalanceAfter:         Money{},
 					CardId:                   "",
 					Type:                     "",
-					Amount:                   0,
+					Amount:                   Money{},
 					Currency:                 "",
 					ErrorDescription:         "",
 					CreatedAt:                "",
@@ -861,7 +863,7 @@ func TestClient_GetFailedTransactions(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// cl.SetHTTPClient(&tt.mockHttpClient)
+			cl.SetHTTPClient(&tt.mockHttpClient)
 			got, err := cl.GetFailedTransactions(tt.args.p)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetFailedTransactions() error = %v, wantErr %v", err, tt.wantErr)
@@ -911,11 +913,11 @@ func TestClient_GetIntegratorDeposit(t *testing.T) {
 				depositId: "",
 			},
 			want: DepositResp{
-				Message: "",
+				Message: "Ok",
 				Data: D3{
 					DepositId:    "",
 					U54DepositId: "",
-					Amount:       0,
+					Amount:       Money{},
 					Currency:     "",
 					Status:       "",
 				},
@@ -975,12 +977,12 @@ func TestClient_GetIntegratorFloat(t *testing.T) {
 				currency: "",
 			},
 			want: FloatResp{
-				Message: "",
+				Message: "Ok",
 				Data: D6{
 					FloatId:   "",
 					UpdatedAt: "",
 					Currency:  "",
-					Balance:   0,
+					Balance:   Money{},
 					IsDefault: false,
 				},
 			},
@@ -1039,7 +1041,7 @@ func TestClient_GetIntegratorFloats(t *testing.T) {
 				currencies: []string{},
 			},
 			want: FloatsResp{
-				Message: "",
+				Message: "Ok",
 				Data:    nil,
 			},
 			wantErr: false,
@@ -1068,7 +1070,7 @@ func TestClient_CreateUser(t *testing.T) {
 		name           string
 		mockHttpClient MockHttpClient
 		args           args
-		want           createUserResp
+		want           CreateUserResp
 		wantErr        bool
 	}{
 		{
@@ -1108,9 +1110,9 @@ func TestClient_CreateUser(t *testing.T) {
 					PostalCode: "900888",
 				},
 			},
-			want: createUserResp{
+			want: CreateUserResp{
 				Message: "Ok",
-				Data: userResp{
+				Data: CreatedUser{
 					UserID: "69a9a77b-5d8d-5738-80eb-ff0b1fb3846a",
 				},
 			},
@@ -1156,7 +1158,7 @@ func TestClient_GetUser(t *testing.T) {
 		}
 	  }
 	`
-	var resp getUserResp
+	var resp UserResp
 	_ = json.Unmarshal([]byte(respJSON), &resp)
 	type args struct {
 		userID string
@@ -1165,7 +1167,7 @@ func TestClient_GetUser(t *testing.T) {
 		name           string
 		mockHttpClient MockHttpClient
 		args           args
-		want           getUserResp
+		want           UserResp
 		wantErr        bool
 	}{
 		{
@@ -1210,6 +1212,27 @@ func TestClient_GetUser(t *testing.T) {
 	}
 }
 
+func TestUser_UnmarshalJSON(t *testing.T) {
+	var u User
+	err := json.Unmarshal([]byte(`{"createdAt":"2022-05-26T14:47:07.089Z","firstName":"Chijioke","uid":"b@gmail.com","virtualCardCount":2,"physicalCardCount":1,"selfieUploaded":true,"idUploaded":true,"active":true}`), &u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := User{
+		CreatedAt:   time.Date(2022, 5, 26, 14, 47, 7, 89000000, time.UTC),
+		UserProfile: UserProfile{FirstName: "Chijioke", UID: "b@gmail.com"},
+		KYCStatus:   KYCStatus{SelfieUploaded: true, IDUploaded: true, Active: true},
+		CardCounts:  CardCounts{PhysicalCardCount: 1, VirtualCardCount: 2},
+	}
+	if !reflect.DeepEqual(u, want) {
+		t.Errorf("User = %+v, want %+v", u, want)
+	}
+
+	if !u.DocumentsUploaded() || u.Total() != 3 {
+		t.Errorf("DocumentsUploaded() = %v, Total() = %d; want true, 3", u.DocumentsUploaded(), u.Total())
+	}
+}
 func TestClient_UpdateUserAddress(t *testing.T) {
 	respJSON := `
 	{
@@ -1219,7 +1242,7 @@ func TestClient_UpdateUserAddress(t *testing.T) {
 		}
 	  }
 	`
-	var resp updateUserAddressResp
+	var resp UpdateUserAddressResp
 	_ = json.Unmarshal([]byte(respJSON), &resp)
 	type args struct {
 		UpdateUserAddressData
@@ -1228,7 +1251,7 @@ func TestClient_UpdateUserAddress(t *testing.T) {
 		name           string
 		mockHttpClient MockHttpClient
 		args           args
-		want           updateUserAddressResp
+		want           UpdateUserAddressResp
 		wantErr        bool
 	}{
 		{
@@ -1288,7 +1311,7 @@ func TestClient_GetCardUserDocURL(t *testing.T) {
 		}
 	  	}
 	  `
-	var resp getCardUserDocURLResp
+	var resp CardUserDocURLResp
 	_ = json.Unmarshal([]byte(respJSON), &resp)
 
 	type args struct {
@@ -1298,7 +1321,7 @@ func TestClient_GetCardUserDocURL(t *testing.T) {
 		name           string
 		mockHttpClient MockHttpClient
 		args           args
-		want           getCardUserDocURLResp
+		want           CardUserDocURLResp
 		wantErr        bool
 	}{
 		{
@@ -1345,8 +1368,7 @@ func TestClient_GetCardUserDocURL(t *testing.T) {
 func TestClient_PostIntegratorDeposit(t *testing.T) {
 
 	type args struct {
-		amount   int
-		currency string
+		amount Money
 	}
 	tests := []struct {
 		name           string
@@ -1396,24 +1418,23 @@ func TestClient_PostIntegratorDeposit(t *testing.T) {
 				},
 			},
 			args: args{
-				amount:   0,
-				currency: "",
+				amount: MustParseMoney("1000", "USD"),
 			},
 			want: PostDepositResp{
 				Message: "Ok",
 				Data: D5{
 					U54DepositId: "",
-					DepositId:    "",
-					Amount:       0,
-					Currency:     "",
-					CreatedAt:    "",
+					DepositId:    "265bee19-f533-4f6c-8076-4189950efeb2",
+					Amount:       MustParseMoney("1000", "USD"),
+					Currency:     "USD",
+					CreatedAt:    "2022-06-01T13:04:46.362Z",
 					Usd: Usd{
-						AccountNumber: "",
-						AccountName:   "",
-						BankName:      "",
-						BankAddress:   "",
-						BranchCode:    "",
-						SwiftCode:     "",
+						AccountNumber: "Nan",
+						AccountName:   "Nan",
+						BankName:      "Nan",
+						BankAddress:   "Nan",
+						BranchCode:    "Nan",
+						SwiftCode:     "Nan",
 					},
 					Btc: Coin{
 						"",
@@ -1439,7 +1460,7 @@ func TestClient_PostIntegratorDeposit(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cl.SetHTTPClient(&tt.mockHttpClient)
-			got, err := cl.PostIntegratorDeposit(tt.args.amount, tt.args.currency)
+			got, err := cl.PostIntegratorDeposit(tt.args.amount)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("PostIntegratorDeposit() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -1525,7 +1546,10 @@ func TestClient_GetTransaction(t *testing.T) {
 						DebitCurrency:  "USD",
 						ConversionRate: 1,
 						Type:           "debit",
-						Amount:         250,
+						Amount:         MustParseMoney("250", "USD"),
+
+						TransactionBalanceBefore: Money{Currency: "USD"},
+						CardBalanceAfter:         Money{Currency: "USD"},
 					},
 					{
 						TransactionId:  "e891d291-f76b-4e51-affa-8bd9c3e1d1b5",
@@ -1533,7 +1557,10 @@ func TestClient_GetTransaction(t *testing.T) {
 						ConversionRate: 1,
 						CreditCurrency: "USD",
 						Type:           "credit",
-						Amount:         1000,
+						Amount:         MustParseMoney("1000", "USD"),
+
+						TransactionBalanceBefore: Money{Currency: "USD"},
+						CardBalanceAfter:         Money{Currency: "USD"},
 					},
 				},
 			},
@@ -1572,3 +1599,53 @@ func TestClient_GetTransaction(t *testing.T) {
 // TestClient_UpdateUserAddress
 // TestClient_PostIntegratorDeposit
 // TestClient_GetCardUserDocURL
+
+func TestClient_GetCardContext(t *testing.T) {
+	type ctxKey struct{}
+
+	tests := []struct {
+		name    string
+		ctx     func() context.Context
+		wantErr error
+	}{
+		{
+			name: "passes the caller's context to the transport",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), ctxKey{}, "request-scoped")
+			},
+		},
+		{
+			name: "does not complete a call whose context is cancelled",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			wantErr: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx()
+			cl.SetHTTPClient(&MockHttpClient{
+				DoFunc: func(r *http.Request) (*http.Response, error) {
+					if r.Context() != ctx {
+						t.Errorf("Expected request to carry the caller's context")
+					}
+					if err := r.Context().Err(); err != nil {
+						return nil, err
+					}
+
+					return &http.Response{
+						StatusCode: 200,
+						Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"message":"Ok"}`))),
+					}, nil
+				},
+			})
+			_, err := cl.GetCardContext(ctx, "c954e4c9-8ca8-4d1d-8ebf-bb374e9f1b1d", "147203800064758")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetCardContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
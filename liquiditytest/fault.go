@@ -0,0 +1,202 @@
+package liquiditytest
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// Fault is what the server does instead of, or before, serving a request.
+type Fault struct {
+	// Latency delays the response. A fault with only Latency set serves the
+	// request normally once the delay is over.
+	Latency time.Duration
+
+	// Status, when non-zero, is returned instead of serving the request.
+	Status int
+
+	// AfterServing serves the request before returning Status, as when the
+	// API acted on a request but the response was lost.
+	AfterServing bool
+
+	// RetryAfter sets the Retry-After header, in whole seconds.
+	RetryAfter time.Duration
+
+	// Message is the error message. ValidationIssues are sent alongside it.
+	Message          string
+	ValidationIssues []liquidity.ValidationIssue
+
+	// Body, when set, is sent verbatim instead of a JSON error, e.g. to
+	// return malformed JSON or an HTML error page.
+	Body        string
+	ContentType string
+}
+
+// ServerError fails with a 500.
+func ServerError() Fault {
+	return Fault{Status: http.StatusInternalServerError, Message: "Internal Server Error"}
+}
+
+// RateLimited fails with a 429 asking the client to wait retryAfter.
+func RateLimited(retryAfter time.Duration) Fault {
+	return Fault{Status: http.StatusTooManyRequests, RetryAfter: retryAfter, Message: "Too Many Requests"}
+}
+
+// Slow delays the response by d and then serves the request normally.
+func Slow(d time.Duration) Fault {
+	return Fault{Latency: d}
+}
+
+// LostResponse serves the request and then fails with a 504, as when a
+// gateway times out after the API has already acted.
+func LostResponse() Fault {
+	return Fault{Status: http.StatusGatewayTimeout, Message: "Gateway Timeout", AfterServing: true}
+}
+
+// MalformedJSON succeeds with a body that is not valid JSON.
+func MalformedJSON() Fault {
+	return Fault{Status: http.StatusOK, Body: `{"message":"Ok","data":{`, ContentType: "application/json"}
+}
+
+// BadGateway fails with an HTML 502 page, as a proxy in front of the API
+// would.
+func BadGateway() Fault {
+	return Fault{Status: http.StatusBadGateway, Body: "<html><body><h1>502 Bad Gateway</h1></body></html>", ContentType: "text/html"}
+}
+
+// ValidationFailure fails with a 400 reporting fields as required, shaped
+// like the API's own validation errors.
+func ValidationFailure(fields ...string) Fault {
+	_, body := required(fields...)
+	e := body.(errorBody)
+
+	return Fault{Status: http.StatusBadRequest, Message: e.Message, ValidationIssues: e.ValidationError}
+}
+
+// InsufficientFloat declines a top-up as if the float had run dry.
+func InsufficientFloat() Fault {
+	return Fault{Status: http.StatusBadRequest, Message: "Insufficient float balance"}
+}
+
+// Rule applies a Fault to matching requests. Rules are checked in the order
+// they were added and the first one that applies wins.
+type Rule struct {
+	// Method and Path select requests, e.g. "PATCH" and
+	// "/card/v1/credit/balance". Empty values match anything.
+	Method string
+	Path   string
+
+	// Nth restricts the rule to the given matching requests, counted from 1
+	// per rule. Empty means every matching request.
+	Nth []int
+
+	// Probability applies the rule to a share of matching requests, using
+	// the server's seeded random source. Zero means always.
+	Probability float64
+
+	// Times caps how often the rule applies. Zero means no cap.
+	Times int
+
+	Fault Fault
+}
+
+type rule struct {
+	Rule
+
+	seen    int
+	applied int
+}
+
+// match reports whether the rule applies to r and counts it.
+func (ru *rule) match(r *http.Request, rnd *rand.Rand) bool {
+	if (ru.Method != "" && ru.Method != r.Method) || (ru.Path != "" && ru.Path != r.URL.Path) {
+		return false
+	}
+
+	ru.seen++
+
+	if ru.Times > 0 && ru.applied >= ru.Times {
+		return false
+	}
+
+	if len(ru.Nth) > 0 && !containsInt(ru.Nth, ru.seen) {
+		return false
+	}
+
+	if ru.Probability > 0 && rnd.Float64() >= ru.Probability {
+		return false
+	}
+
+	ru.applied++
+
+	return true
+}
+
+// AddRule adds fault injection rules.
+func (s *Server) AddRule(rules ...Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range rules {
+		s.rules = append(s.rules, &rule{Rule: r})
+	}
+}
+
+// ClearRules removes all fault injection rules.
+func (s *Server) ClearRules() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules = nil
+}
+
+// SetSeed reseeds the random source behind Rule.Probability, so a run can be
+// reproduced.
+func (s *Server) SetSeed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faultRand = rand.New(rand.NewSource(seed))
+}
+
+// fault returns the fault to apply to r, if any. It runs with s.mu held.
+func (s *Server) fault(r *http.Request) (Fault, bool) {
+	for _, ru := range s.rules {
+		if ru.match(r, s.faultRand) {
+			return ru.Fault, true
+		}
+	}
+
+	return Fault{}, false
+}
+
+// write sends the fault's response.
+func (f Fault) write(w http.ResponseWriter) {
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+	}
+
+	if f.Body == "" {
+		writeJSON(w, f.Status, errorBody{Message: f.Message, ValidationError: f.ValidationIssues})
+		return
+	}
+
+	if f.ContentType != "" {
+		w.Header().Set("Content-Type", f.ContentType)
+	}
+	w.WriteHeader(f.Status)
+	w.Write([]byte(f.Body))
+}
+
+func containsInt(list []int, v int) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+
+	return false
+}
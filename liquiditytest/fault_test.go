@@ -0,0 +1,182 @@
+package liquiditytest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+func TestServer_NthCallFails(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.AddRule(Rule{Method: http.MethodGet, Path: "/integrator/v1/floats", Nth: []int{1, 2}, Fault: ServerError()})
+
+	cl := srv.Client(liquidity.WithRetryPolicy(liquidity.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+
+	if _, err := cl.GetIntegratorFloats(nil); err != nil {
+		t.Fatalf("GetIntegratorFloats() error = %v", err)
+	}
+	if got := srv.Calls(http.MethodGet, "/integrator/v1/floats"); got != 3 {
+		t.Errorf("GET /integrator/v1/floats called %d times, want 3", got)
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+	tests := []struct {
+		name   string
+		fault  Fault
+		status int
+		check  func(t *testing.T, err error)
+	}{
+		{
+			name:   "rate limited",
+			fault:  RateLimited(2 * time.Second),
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name:   "bad gateway",
+			fault:  BadGateway(),
+			status: http.StatusBadGateway,
+			check: func(t *testing.T, err error) {
+				var apiErr *liquidity.APIError
+				if errors.As(err, &apiErr) && apiErr.ContentType != "text/html" {
+					t.Errorf("ContentType = %q, want text/html", apiErr.ContentType)
+				}
+			},
+		},
+		{
+			name:   "validation",
+			fault:  ValidationFailure("currency", "id"),
+			status: http.StatusBadRequest,
+			check: func(t *testing.T, err error) {
+				var apiErr *liquidity.APIError
+				if errors.As(err, &apiErr) && len(apiErr.ValidationIssues) != 2 {
+					t.Errorf("ValidationIssues = %v, want 2 issues", apiErr.ValidationIssues)
+				}
+			},
+		},
+		{
+			name:   "insufficient float",
+			fault:  InsufficientFloat(),
+			status: http.StatusBadRequest,
+			check: func(t *testing.T, err error) {
+				if !liquidity.IsInsufficientFunds(err) {
+					t.Errorf("error = %v, want insufficient funds", err)
+				}
+			},
+		},
+		{
+			name:  "malformed JSON",
+			fault: MalformedJSON(),
+			check: func(t *testing.T, err error) {
+				if err == nil {
+					t.Error("error = nil, want a decode error")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer()
+			defer srv.Close()
+
+			srv.AddRule(Rule{Path: "/integrator/v1/float", Fault: tt.fault})
+
+			_, err := srv.Client().GetIntegratorFloat("USD")
+
+			var apiErr *liquidity.APIError
+			if tt.status != 0 && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.status) {
+				t.Fatalf("GetIntegratorFloat() error = %v, want status %d", err, tt.status)
+			}
+			if tt.check != nil {
+				tt.check(t, err)
+			}
+		})
+	}
+}
+
+func TestServer_RetryAfterHeader(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.AddRule(Rule{Fault: RateLimited(1500 * time.Millisecond)})
+
+	r, err := http.Get(srv.URL + "/integrator/v1/floats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+
+	if got := r.Header.Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+}
+
+func TestServer_Latency(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.AddRule(Rule{Fault: Slow(time.Second)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := srv.Client().GetIntegratorFloatsContext(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetIntegratorFloatsContext() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestServer_RuleTimes(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.AddRule(Rule{Fault: ServerError(), Times: 1})
+	cl := srv.Client()
+
+	if _, err := cl.GetIntegratorFloats(nil); err == nil {
+		t.Error("first call succeeded, want a 500")
+	}
+	if _, err := cl.GetIntegratorFloats(nil); err != nil {
+		t.Errorf("second call error = %v", err)
+	}
+}
+
+func TestServer_ProbabilityIsSeeded(t *testing.T) {
+	failures := func(seed int64) []bool {
+		srv := NewServer()
+		defer srv.Close()
+
+		srv.SetSeed(seed)
+		srv.AddRule(Rule{Probability: 0.5, Fault: ServerError()})
+		cl := srv.Client()
+
+		var got []bool
+		for i := 0; i < 20; i++ {
+			_, err := cl.GetIntegratorFloats(nil)
+			got = append(got, err != nil)
+		}
+
+		return got
+	}
+
+	a, b := failures(42), failures(42)
+
+	n := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("call %d failed in one run but not the other", i+1)
+		}
+		if a[i] {
+			n++
+		}
+	}
+	if n == 0 || n == len(a) {
+		t.Errorf("%d of %d calls failed, want some but not all", n, len(a))
+	}
+}
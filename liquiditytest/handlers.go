@@ -0,0 +1,736 @@
+package liquiditytest
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+var (
+	errInvalidLimit = errors.New("Invalid limit")
+	errInvalidLek   = errors.New("Invalid lek")
+)
+
+// card statuses
+const (
+	statusIssued  = "issued"
+	statusFrozen  = "frozen"
+	statusStopped = "stopped"
+)
+
+func (s *Server) registerIntegrator(r *http.Request) (int, interface{}) {
+	var data liquidity.RegisterIntegratorData
+	if err := decodeBody(r, &data); err != nil {
+		return fail(http.StatusBadRequest, "Invalid body")
+	}
+
+	var missing []string
+	if data.BusinessName == "" {
+		missing = append(missing, "businessName")
+	}
+	if data.Email == "" {
+		missing = append(missing, "email")
+	}
+	if len(missing) > 0 {
+		return required(missing...)
+	}
+
+	if s.integratorID == "" {
+		s.integratorID = s.newID()
+	}
+	s.webhook = data.WebhookUrl
+
+	for _, currency := range data.FloatCurrencies {
+		s.ensureFloat(currency)
+	}
+
+	return http.StatusCreated, dataBody{Message: "Ok", Data: liquidity.D1{IntegratorId: s.integratorID}}
+}
+
+func (s *Server) updateWebhook(r *http.Request) (int, interface{}) {
+	var data struct {
+		Webhook string `json:"webhook"`
+	}
+	if err := decodeBody(r, &data); err != nil {
+		return fail(http.StatusBadRequest, "Invalid body")
+	}
+	if data.Webhook == "" {
+		return required("webhook")
+	}
+
+	s.webhook = data.Webhook
+
+	return ok(nil)
+}
+
+func (s *Server) createCard(r *http.Request) (int, interface{}) {
+	var data liquidity.CreateCardData
+	if err := decodeBody(r, &data); err != nil {
+		return fail(http.StatusBadRequest, "Invalid body")
+	}
+	if data.UserId == "" || data.Expiry == "" {
+		return required(missingFields(map[string]string{"userId": data.UserId, "expiry": data.Expiry})...)
+	}
+
+	u, found := s.users[data.UserId]
+	if !found {
+		return fail(http.StatusNotFound, "User not found")
+	}
+
+	expiry, err := time.Parse("2006-01-02", data.Expiry)
+	if err != nil || !expiry.After(s.now()) {
+		return fail(http.StatusBadRequest, "Expiry must be a future date in the format YYYY-MM-DD")
+	}
+	expiry = expiry.AddDate(0, 0, 1)
+
+	number := "5368" + s.digits(12)
+	c := &card{
+		UserID: u.ID,
+		D2: liquidity.D2{
+			CardId:         s.newID(),
+			Expiry:         expiry.Format("2006-01-02T15:04:05.000Z"),
+			Valid:          expiry.Format("01/06"),
+			Cvv2:           s.digits(3),
+			CardNumber:     number,
+			Last4:          number[len(number)-4:],
+			TrackingNumber: s.digits(15),
+			Balance:        liquidity.NewMoney(0, "USD"),
+			Status:         statusIssued,
+			Currency:       "USD",
+			SingleUse:      data.SingleUse,
+			CardName:       strings.TrimSpace(u.FirstName + " " + u.LastName),
+			CreatedAt:      s.timestamp(),
+		},
+	}
+
+	s.cards[c.CardId] = c
+	s.cardOrder = append(s.cardOrder, c.CardId)
+	u.VirtualCardCount++
+
+	return ok(c.D2)
+}
+
+func (s *Server) getCard(r *http.Request) (int, interface{}) {
+	q := r.URL.Query()
+
+	c, found := s.cards[q.Get("card")]
+	if !found || (q.Get("trackingNumber") != "" && q.Get("trackingNumber") != c.TrackingNumber) {
+		return fail(http.StatusNotFound, "Card not found")
+	}
+
+	return ok(c.D2)
+}
+
+func (s *Server) getCards(r *http.Request) (int, interface{}) {
+	q := r.URL.Query()
+
+	var cards []liquidity.D2
+	for _, id := range s.cardOrder {
+		c := s.cards[id]
+		if q.Get("user") != "" && c.UserID != q.Get("user") {
+			continue
+		}
+		if q.Get("type") != "" && q.Get("type") != "virtual" {
+			continue
+		}
+		if !withinDates(c.CreatedAt, q.Get("startDate"), q.Get("endDate")) {
+			continue
+		}
+
+		d := c.D2
+		d.CardNumber = ""
+		cards = append(cards, d)
+	}
+
+	ids := make([]string, len(cards))
+	for i, c := range cards {
+		ids[i] = c.CardId
+	}
+
+	from, to, lek, err := page(ids, q.Get("limit"), q.Get("lek"))
+	if err != nil {
+		return fail(http.StatusBadRequest, err.Error())
+	}
+
+	return http.StatusOK, dataBody{Message: "Ok", Data: nonNil(cards[from:to]), Lek: lek}
+}
+
+type balanceChange struct {
+	CardId string          `json:"cardId"`
+	Amount liquidity.Money `json:"amount"`
+}
+
+func (s *Server) topUp(r *http.Request) (int, interface{}) {
+	return s.moveBalance(r, "credit")
+}
+
+func (s *Server) debit(r *http.Request) (int, interface{}) {
+	return s.moveBalance(r, "debit")
+}
+
+// moveBalance credits or debits a card against the float in the card's
+// currency, recording the transaction, or a failed one when funds are short.
+func (s *Server) moveBalance(r *http.Request, kind string) (int, interface{}) {
+	var data balanceChange
+	if err := decodeBody(r, &data); err != nil {
+		return fail(http.StatusBadRequest, "Invalid body")
+	}
+	if data.CardId == "" {
+		return required("cardId")
+	}
+
+	c, found := s.cards[data.CardId]
+	if !found {
+		return fail(http.StatusNotFound, "Card not found")
+	}
+
+	amount := data.Amount
+	amount.Currency = c.Currency
+	if amount.Sign() <= 0 {
+		return fail(http.StatusBadRequest, "Amount must be positive")
+	}
+	if _, err := amount.MinorUnits(); err != nil {
+		return fail(http.StatusBadRequest, "Amount has too many decimal places")
+	}
+	if c.Status != statusIssued {
+		return fail(http.StatusBadRequest, "Card is %s", c.Status)
+	}
+
+	float := s.ensureFloat(c.Currency)
+
+	from, to := &float.Balance, &c.Balance
+	if kind == "debit" {
+		from, to = to, from
+	}
+
+	if from.Cmp(amount) < 0 {
+		reason := "Insufficient float balance"
+		if kind == "debit" {
+			reason = "Insufficient card balance"
+		}
+		s.failed = append(s.failed, s.transaction(c, kind, amount, c.Balance, c.Balance, reason))
+		return fail(http.StatusBadRequest, reason)
+	}
+
+	before := c.Balance
+	*from, _ = from.Sub(amount)
+	*to, _ = to.Add(amount)
+	float.UpdatedAt = s.timestamp()
+
+	s.txns[c.CardId] = append(s.txns[c.CardId], s.transaction(c, kind, amount, before, c.Balance, ""))
+
+	return ok(c.D2)
+}
+
+func (s *Server) transaction(c *card, kind string, amount, before, after liquidity.Money, reason string) liquidity.D4 {
+	t := liquidity.D4{
+		TransactionId:            s.newID(),
+		ConversionRate:           1,
+		TransactionBalanceBefore: before,
+		CardBalanceAfter:         after,
+		CardId:                   c.CardId,
+		Type:                     kind,
+		Amount:                   amount,
+		Currency:                 c.Currency,
+		ErrorDescription:         reason,
+		CreatedAt:                s.timestamp(),
+	}
+
+	if kind == "debit" {
+		t.DebitId = c.CardId
+		t.DebitCurrency = c.Currency
+		t.Narrative = "Card debit"
+	} else {
+		t.CreditCurrency = c.Currency
+		t.Narrative = "Card top-up"
+	}
+
+	return t
+}
+
+type cardStatusChange struct {
+	CardId   string `json:"cardId"`
+	ReasonId int    `json:"reasonId"`
+}
+
+func (s *Server) freeze(r *http.Request) (int, interface{}) {
+	return s.setStatus(r, statusIssued, statusFrozen)
+}
+
+func (s *Server) unfreeze(r *http.Request) (int, interface{}) {
+	return s.setStatus(r, statusFrozen, statusIssued)
+}
+
+func (s *Server) stop(r *http.Request) (int, interface{}) {
+	return s.setStatus(r, "", statusStopped)
+}
+
+// setStatus moves a card to status. An empty from accepts any card that is
+// not stopped.
+func (s *Server) setStatus(r *http.Request, from, status string) (int, interface{}) {
+	var data cardStatusChange
+	if err := decodeBody(r, &data); err != nil {
+		return fail(http.StatusBadRequest, "Invalid body")
+	}
+	if data.CardId == "" {
+		return required("cardId")
+	}
+
+	c, found := s.cards[data.CardId]
+	if !found {
+		return fail(http.StatusNotFound, "Card not found")
+	}
+
+	if c.Status == statusStopped || (from != "" && c.Status != from) {
+		return fail(http.StatusBadRequest, "Card is %s", c.Status)
+	}
+
+	c.Status = status
+
+	return ok(nil)
+}
+
+func (s *Server) getFailedTransaction(r *http.Request) (int, interface{}) {
+	id := r.URL.Query().Get("transaction")
+	for _, t := range s.failed {
+		if t.TransactionId == id {
+			return ok(t)
+		}
+	}
+
+	return fail(http.StatusNotFound, "Transaction not found")
+}
+
+func (s *Server) getFailedTransactions(r *http.Request) (int, interface{}) {
+	return s.listTransactions(r, s.failed)
+}
+
+func (s *Server) getTransactions(r *http.Request) (int, interface{}) {
+	card := r.URL.Query().Get("card")
+	if card == "" {
+		return required("card")
+	}
+	if _, found := s.cards[card]; !found {
+		return fail(http.StatusNotFound, "Card not found")
+	}
+
+	return s.listTransactions(r, s.txns[card])
+}
+
+func (s *Server) listTransactions(r *http.Request, all []liquidity.D4) (int, interface{}) {
+	q := r.URL.Query()
+
+	var txns []liquidity.D4
+	for _, t := range all {
+		if q.Get("card") != "" && t.CardId != q.Get("card") {
+			continue
+		}
+		if !withinDates(t.CreatedAt, q.Get("startDate"), q.Get("endDate")) {
+			continue
+		}
+		txns = append(txns, t)
+	}
+
+	ids := make([]string, len(txns))
+	for i, t := range txns {
+		ids[i] = t.TransactionId
+	}
+
+	from, to, lek, err := page(ids, q.Get("limit"), q.Get("lek"))
+	if err != nil {
+		return fail(http.StatusBadRequest, err.Error())
+	}
+
+	return http.StatusOK, dataBody{Message: "Ok", Data: nonNilTxns(txns[from:to]), Lek: lek}
+}
+
+func (s *Server) getDeposit(r *http.Request) (int, interface{}) {
+	d, found := s.deposits[r.URL.Query().Get("deposit")]
+	if !found {
+		return fail(http.StatusNotFound, "Deposit not found")
+	}
+
+	return ok(liquidity.D3{
+		DepositId: d.DepositId,
+		Amount:    d.Amount,
+		Currency:  d.Currency,
+		Status:    depositStatus(d),
+	})
+}
+
+func (s *Server) postDeposit(r *http.Request) (int, interface{}) {
+	var data struct {
+		Amount   liquidity.Money `json:"amount"`
+		Currency string          `json:"currency"`
+	}
+	if err := decodeBody(r, &data); err != nil {
+		return fail(http.StatusBadRequest, "Invalid body")
+	}
+	if data.Currency == "" {
+		return required("currency")
+	}
+	if data.Amount.Sign() <= 0 {
+		return fail(http.StatusBadRequest, "Amount must be positive")
+	}
+
+	data.Amount.Currency = data.Currency
+	d := &liquidity.D5{
+		DepositId: s.newID(),
+		Amount:    data.Amount,
+		Currency:  data.Currency,
+		CreatedAt: s.timestamp(),
+	}
+
+	if data.Currency == "USD" {
+		d.Usd = liquidity.Usd{
+			AccountNumber: s.digits(10),
+			AccountName:   "One Liquidity Sandbox",
+			BankName:      "Sandbox Bank",
+			BankAddress:   "1 Test Street",
+			BranchCode:    s.digits(6),
+			SwiftCode:     "SANDUS33",
+		}
+	} else {
+		wallet := liquidity.Coin{WalletAddress: "0x" + s.digits(40)}
+		switch data.Currency {
+		case "BTC":
+			d.Btc = liquidity.Coin{WalletAddress: "tb1q" + s.digits(38)}
+		case "ETH":
+			d.Eth = wallet
+		case "BUSD":
+			d.Busd = wallet
+		case "USDC":
+			d.Usdc = wallet
+		case "USDT":
+			d.Usdt = wallet
+		}
+	}
+
+	s.deposits[d.DepositId] = d
+
+	return ok(d)
+}
+
+func (s *Server) getFloats(r *http.Request) (int, interface{}) {
+	var currencies []string
+	for _, currency := range r.URL.Query()["currencies"] {
+		currencies = append(currencies, strings.ToUpper(currency))
+	}
+
+	var floats []liquidity.D6
+	for _, currency := range sortedKeys(s.floats) {
+		if len(currencies) > 0 && !contains(currencies, currency) {
+			continue
+		}
+		floats = append(floats, *s.floats[currency])
+	}
+
+	if floats == nil {
+		floats = []liquidity.D6{}
+	}
+
+	return ok(floats)
+}
+
+func (s *Server) getFloat(r *http.Request) (int, interface{}) {
+	f, found := s.floats[strings.ToUpper(r.URL.Query().Get("currency"))]
+	if !found {
+		return fail(http.StatusNotFound, "Float not found")
+	}
+
+	return ok(*f)
+}
+
+func (s *Server) updateFloatDefault(r *http.Request) (int, interface{}) {
+	var data struct {
+		Id string `json:"id"`
+	}
+	if err := decodeBody(r, &data); err != nil {
+		return fail(http.StatusBadRequest, "Invalid body")
+	}
+
+	var target *liquidity.D6
+	for _, f := range s.floats {
+		if f.FloatId == data.Id {
+			target = f
+		}
+	}
+	if target == nil {
+		return fail(http.StatusNotFound, "Float not found")
+	}
+
+	for _, f := range s.floats {
+		f.IsDefault = f == target
+	}
+
+	return ok(nil)
+}
+
+func (s *Server) getUser(r *http.Request) (int, interface{}) {
+	u, found := s.users[r.URL.Query().Get("userId")]
+	if !found {
+		return fail(http.StatusNotFound, "User not found")
+	}
+
+	return ok(u)
+}
+
+func (s *Server) createUser(r *http.Request) (int, interface{}) {
+	var data liquidity.CreateUserData
+	if err := decodeBody(r, &data); err != nil {
+		return fail(http.StatusBadRequest, "Invalid body")
+	}
+
+	missing := missingFields(map[string]string{
+		"firstName":  data.FirstName,
+		"lastName":   data.LastName,
+		"kycCountry": data.KycCountry,
+		"uid":        data.UID,
+	})
+	if len(missing) > 0 {
+		return required(missing...)
+	}
+
+	for _, u := range s.users {
+		if u.UID == data.UID {
+			return fail(http.StatusConflict, "User already exists")
+		}
+	}
+
+	u := s.addUser(data)
+
+	return ok(map[string]string{"userId": u.ID})
+}
+
+func (s *Server) updateUserAddress(r *http.Request) (int, interface{}) {
+	var data liquidity.UpdateUserAddressData
+	if err := decodeBody(r, &data); err != nil {
+		return fail(http.StatusBadRequest, "Invalid body")
+	}
+	if data.UserID == "" {
+		return required("userId")
+	}
+
+	u, found := s.users[data.UserID]
+	if !found {
+		return fail(http.StatusNotFound, "User not found")
+	}
+
+	if data.KycCountry != "" {
+		u.KycCountry = data.KycCountry
+	}
+	if data.Address != "" {
+		u.Address = data.Address
+	}
+	if data.City != "" {
+		u.City = data.City
+	}
+	if data.PostalCode != "" {
+		u.PostalCode = data.PostalCode
+	}
+	u.UpdatedAt = s.timestamp()
+
+	return ok(map[string]string{"message": "Address updated"})
+}
+
+func (s *Server) getDocURLs(r *http.Request) (int, interface{}) {
+	u, found := s.users[r.URL.Query().Get("user")]
+	if !found {
+		return fail(http.StatusNotFound, "User not found")
+	}
+
+	return ok(map[string]string{
+		"selfieUploadUrl": s.URL + uploadPrefix + u.ID + "/selfie",
+		"idUploadUrl":     s.URL + uploadPrefix + u.ID + "/id",
+		"uid":             u.UID,
+	})
+}
+
+// upload accepts a PUT to a pre-signed document URL. Once both documents are
+// in, the user is OFAC checked and activated, unless FailOFAC was called.
+func (s *Server) upload(r *http.Request) (int, interface{}) {
+	if r.Method != http.MethodPut {
+		return fail(http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, uploadPrefix), "/")
+	if len(parts) != 2 {
+		return fail(http.StatusNotFound, "Not Found")
+	}
+
+	u, found := s.users[parts[0]]
+	if !found {
+		return fail(http.StatusNotFound, "Not Found")
+	}
+
+	if r.ContentLength == 0 {
+		return fail(http.StatusBadRequest, "Empty upload")
+	}
+
+	switch parts[1] {
+	case "selfie":
+		u.SelfieUploaded = true
+	case "id":
+		u.IDUploaded = true
+	default:
+		return fail(http.StatusNotFound, "Not Found")
+	}
+
+	if u.SelfieUploaded && u.IDUploaded && !u.OfacChecked {
+		u.OfacChecked = true
+		u.OfacFail = u.failOFAC
+		u.Active = !u.failOFAC
+	}
+	u.UpdatedAt = s.timestamp()
+
+	return http.StatusOK, nil
+}
+
+// ensureFloat returns the float for currency, creating an empty one.
+func (s *Server) ensureFloat(currency string) *liquidity.D6 {
+	currency = strings.ToUpper(currency)
+
+	f, found := s.floats[currency]
+	if !found {
+		f = &liquidity.D6{
+			FloatId:   s.newID(),
+			UpdatedAt: s.timestamp(),
+			Currency:  currency,
+			Balance:   liquidity.NewMoney(0, currency),
+			IsDefault: len(s.floats) == 0,
+		}
+		s.floats[currency] = f
+	}
+
+	return f
+}
+
+func depositStatus(d *liquidity.D5) string {
+	if d.U54DepositId != "" {
+		return "confirmed"
+	}
+
+	return "pending"
+}
+
+// page returns the bounds of the page after the item with id lek, and the
+// cursor of the page after it.
+func page(ids []string, limit, lek string) (int, int, string, error) {
+	n := defaultPageSize
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 0 {
+			return 0, 0, "", errInvalidLimit
+		}
+		if l > 0 {
+			n = l
+		}
+	}
+
+	from := 0
+	if lek != "" {
+		from = -1
+		for i, id := range ids {
+			if id == lek {
+				from = i + 1
+			}
+		}
+		if from < 0 {
+			return 0, 0, "", errInvalidLek
+		}
+	}
+
+	to := from + n
+	if to >= len(ids) {
+		return from, len(ids), "", nil
+	}
+
+	return from, to, ids[to-1], nil
+}
+
+// withinDates reports whether the timestamp at falls between start and end,
+// given as dates or timestamps. Empty bounds are open.
+func withinDates(at, start, end string) bool {
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return true
+	}
+
+	if s, ok := parseDate(start); ok && t.Before(s) {
+		return false
+	}
+
+	if e, ok := parseDate(end); ok {
+		if len(end) == len("2006-01-02") {
+			e = e.AddDate(0, 0, 1)
+		}
+		if !t.Before(e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseDate(v string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func missingFields(fields map[string]string) []string {
+	var missing []string
+	for name, value := range fields {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+func sortedKeys(m map[string]*liquidity.D6) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nonNil(cards []liquidity.D2) []liquidity.D2 {
+	if cards == nil {
+		return []liquidity.D2{}
+	}
+
+	return cards
+}
+
+func nonNilTxns(txns []liquidity.D4) []liquidity.D4 {
+	if txns == nil {
+		return []liquidity.D4{}
+	}
+
+	return txns
+}
@@ -0,0 +1,355 @@
+// Package liquiditytest provides an in-memory fake of the One Liquidity API
+// for tests. Point a liquidity.Client at Server.URL, or use Server.Client, and
+// it behaves like the sandbox: cards, users, floats, deposits and
+// transactions are kept in memory and balances move with every top-up and
+// debit.
+//
+// Rules added with AddRule inject faults such as latency, 5xx and 429
+// responses, malformed bodies and validation errors, so retry and error
+// handling can be rehearsed deterministically.
+package liquiditytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// APIKey is the key the server accepts unless another one is set with
+// SetAPIKey.
+const APIKey = "sk_test_liquiditytest"
+
+// defaultPageSize is used by list endpoints when no limit is given.
+const defaultPageSize = 20
+
+// Server is a stateful fake of the One Liquidity API.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	apiKey  string
+	now     func() time.Time
+	rand    *rand.Rand
+	calls   map[string]int
+	webhook string
+
+	rules     []*rule
+	faultRand *rand.Rand
+
+	integratorID string
+	users        map[string]*user
+	cards        map[string]*card
+	cardOrder    []string
+	floats       map[string]*liquidity.D6
+	deposits     map[string]*liquidity.D5
+	txns         map[string][]liquidity.D4
+	failed       []liquidity.D4
+	idempotent   map[string]idempotentResponse
+}
+
+type user struct {
+	ID                string `json:"-"`
+	CreatedAt         string `json:"createdAt"`
+	UpdatedAt         string `json:"updatedAt"`
+	FirstName         string `json:"firstName"`
+	LastName          string `json:"lastName"`
+	UID               string `json:"uid"`
+	KycCountry        string `json:"kycCountry"`
+	Address           string `json:"address"`
+	City              string `json:"city"`
+	PostalCode        string `json:"postalCode"`
+	PhysicalCardCount int    `json:"physicalCardCount"`
+	VirtualCardCount  int    `json:"virtualCardCount"`
+	SelfieUploaded    bool   `json:"selfieUploaded"`
+	IDUploaded        bool   `json:"idUploaded"`
+	OfacChecked       bool   `json:"ofacChecked"`
+	OfacFail          bool   `json:"ofacFail"`
+	Active            bool   `json:"active"`
+
+	failOFAC bool
+}
+
+type card struct {
+	liquidity.D2
+	UserID string
+}
+
+// NewServer starts a fake server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		apiKey:    APIKey,
+		now:       time.Now,
+		rand:      rand.New(rand.NewSource(1)),
+		faultRand: rand.New(rand.NewSource(1)),
+		calls:     make(map[string]int),
+		users:     make(map[string]*user),
+		cards:     make(map[string]*card),
+		floats:    make(map[string]*liquidity.D6),
+		deposits:  make(map[string]*liquidity.D5),
+		txns:      make(map[string][]liquidity.D4),
+
+		idempotent: make(map[string]idempotentResponse),
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// Client returns a client pointed at the server with its API key. Retries
+// are disabled so failures surface immediately; opts may override that and
+// anything else.
+func (s *Server) Client(opts ...liquidity.Option) *liquidity.Client {
+	s.mu.Lock()
+	key := s.apiKey
+	s.mu.Unlock()
+
+	base := []liquidity.Option{
+		liquidity.WithAPIKey(key),
+		liquidity.WithBaseURL(s.URL),
+		liquidity.WithRetryPolicy(liquidity.NoRetry),
+	}
+
+	c, err := liquidity.New(append(base, opts...)...)
+	if err != nil {
+		panic("liquiditytest: " + err.Error())
+	}
+
+	return c
+}
+
+// SetAPIKey changes the key the server accepts.
+func (s *Server) SetAPIKey(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apiKey = key
+}
+
+// SetClock replaces the server's clock, which stamps createdAt fields.
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+// Calls returns how many requests were made to method and path.
+func (s *Server) Calls(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[method+" "+path]
+}
+
+// route is an endpoint handler. It runs with s.mu held.
+type route func(s *Server, r *http.Request) (int, interface{})
+
+var routes = map[string]route{
+	"POST /integrator/v1/register":         (*Server).registerIntegrator,
+	"PATCH /integrator/v1/webhook":         (*Server).updateWebhook,
+	"POST /card/v1":                        (*Server).createCard,
+	"GET /card/v1":                         (*Server).getCard,
+	"GET /cards/v1":                        (*Server).getCards,
+	"PATCH /card/v1/credit/balance":        (*Server).topUp,
+	"PATCH /card/v1/debit/balance":         (*Server).debit,
+	"PATCH /card/v1/freeze":                (*Server).freeze,
+	"PATCH /card/v1/unfreeze":              (*Server).unfreeze,
+	"PATCH /card/v1/stop":                  (*Server).stop,
+	"GET /card/v1/transaction/failed":      (*Server).getFailedTransaction,
+	"GET /card/v1/transactions/failed":     (*Server).getFailedTransactions,
+	"GET /card/v1/transactions":            (*Server).getTransactions,
+	"GET /integrator/v1/deposit":           (*Server).getDeposit,
+	"POST /integrator/v1/deposit":          (*Server).postDeposit,
+	"GET /integrator/v1/floats":            (*Server).getFloats,
+	"GET /integrator/v1/float":             (*Server).getFloat,
+	"PATCH /integrator/v1/float/default":   (*Server).updateFloatDefault,
+	"GET /card/v1/user":                    (*Server).getUser,
+	"POST /card/v1/user":                   (*Server).createUser,
+	"PATCH /card/v1/user/address":          (*Server).updateUserAddress,
+	"GET /card/v1/user/documentation/urls": (*Server).getDocURLs,
+}
+
+// uploadPrefix serves the pre-signed document upload URLs handed out by
+// GetCardUserDocURL.
+const uploadPrefix = "/_uploads/"
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.calls[r.Method+" "+r.URL.Path]++
+	f, faulty := s.fault(r)
+	s.mu.Unlock()
+
+	if faulty {
+		if f.Latency > 0 {
+			select {
+			case <-time.After(f.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if f.Status != 0 && !f.AfterServing {
+			f.write(w)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, uploadPrefix) {
+		status, body := s.upload(r)
+		writeJSON(w, status, body)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		writeJSON(w, http.StatusUnauthorized, errorBody{Message: "Unauthorized"})
+		return
+	}
+
+	rt, ok := routes[r.Method+" "+r.URL.Path]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorBody{Message: "Not Found"})
+		return
+	}
+
+	status, body := s.serveIdempotent(r, rt)
+
+	if faulty && f.AfterServing {
+		f.write(w)
+		return
+	}
+
+	writeJSON(w, status, body)
+}
+
+// idempotentRoutes are the endpoints that honour Idempotency-Key.
+var idempotentRoutes = map[string]bool{
+	"POST /card/v1":                 true,
+	"PATCH /card/v1/credit/balance": true,
+	"PATCH /card/v1/debit/balance":  true,
+	"POST /integrator/v1/deposit":   true,
+}
+
+// idempotentResponse is the first response to a request with an
+// Idempotency-Key.
+type idempotentResponse struct {
+	request string
+	status  int
+	body    interface{}
+}
+
+// serveIdempotent runs rt, or returns the stored response when the request
+// repeats an Idempotency-Key. A key reused for a different request is
+// rejected.
+func (s *Server) serveIdempotent(r *http.Request, rt route) (int, interface{}) {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" || !idempotentRoutes[r.Method+" "+r.URL.Path] {
+		return rt(s, r)
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fail(http.StatusBadRequest, "Invalid body")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	request := r.Method + " " + r.URL.Path + "\n" + string(data)
+
+	if prev, found := s.idempotent[key]; found {
+		if prev.request != request {
+			return fail(http.StatusUnprocessableEntity, "Idempotency key reused with a different request")
+		}
+		return prev.status, prev.body
+	}
+
+	status, body := rt(s, r)
+
+	// keep the body as sent, not the live records it points to
+	stored, err := json.Marshal(body)
+	if err != nil {
+		return fail(http.StatusInternalServerError, "Internal Server Error")
+	}
+	s.idempotent[key] = idempotentResponse{request: request, status: status, body: json.RawMessage(stored)}
+
+	return status, body
+}
+
+type errorBody struct {
+	Message         string                      `json:"message"`
+	ValidationError []liquidity.ValidationIssue `json:"validationError,omitempty"`
+}
+
+type dataBody struct {
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+	Lek     string      `json:"lek,omitempty"`
+}
+
+func ok(data interface{}) (int, interface{}) {
+	return http.StatusOK, dataBody{Message: "Ok", Data: data}
+}
+
+func fail(status int, format string, args ...interface{}) (int, interface{}) {
+	return status, errorBody{Message: fmt.Sprintf(format, args...)}
+}
+
+// required builds a validation error for missing fields.
+func required(fields ...string) (int, interface{}) {
+	e := errorBody{Message: "Validation error"}
+	for _, f := range fields {
+		e.ValidationError = append(e.ValidationError, liquidity.ValidationIssue{
+			Code:     "invalid_type",
+			Expected: "string",
+			Received: "undefined",
+			Path:     []string{f},
+			Message:  "Required",
+		})
+	}
+
+	return http.StatusBadRequest, e
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func decodeBody(r *http.Request, v interface{}) error {
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+// newID returns a random UUID-shaped identifier.
+func (s *Server) newID() string {
+	var b [16]byte
+	s.rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func (s *Server) digits(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteByte(byte('0' + s.rand.Intn(10)))
+	}
+
+	return b.String()
+}
+
+func (s *Server) timestamp() string {
+	return s.now().UTC().Format("2006-01-02T15:04:05.000Z")
+}
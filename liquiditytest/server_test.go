@@ -0,0 +1,322 @@
+package liquiditytest
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"testing"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+func newCard(t *testing.T, srv *Server, cl *liquidity.Client) string {
+	t.Helper()
+
+	userID := srv.AddUser(liquidity.CreateUserData{FirstName: "Ada", LastName: "Obi", UID: "u-1", KycCountry: "NG"})
+
+	res, err := cl.CreateCard(liquidity.CreateCardData{UserId: userID, Expiry: "2099-01-01"})
+	if err != nil {
+		t.Fatalf("CreateCard() error = %v", err)
+	}
+
+	return res.Data.CardId
+}
+
+func TestServer_TopUpAndDebit(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	cl := srv.Client()
+	srv.SetFloat(liquidity.MustParseMoney("100", "USD"))
+	cardID := newCard(t, srv, cl)
+
+	if _, err := cl.TopUp(cardID, liquidity.MustParseMoney("25.50", "USD")); err != nil {
+		t.Fatalf("TopUp() error = %v", err)
+	}
+
+	res, err := cl.Debit(cardID, liquidity.MustParseMoney("5.25", "USD"))
+	if err != nil {
+		t.Fatalf("Debit() error = %v", err)
+	}
+	if want := liquidity.MustParseMoney("20.25", "USD"); !res.Data.Balance.Equal(want) {
+		t.Errorf("Debit() balance = %v, want %v", res.Data.Balance, want)
+	}
+
+	card, err := cl.GetCard(cardID, "")
+	if err != nil {
+		t.Fatalf("GetCard() error = %v", err)
+	}
+	if want := liquidity.MustParseMoney("20.25", "USD"); !card.Data.Balance.Equal(want) {
+		t.Errorf("GetCard() balance = %v, want %v", card.Data.Balance, want)
+	}
+
+	float, err := cl.GetIntegratorFloat("USD")
+	if err != nil {
+		t.Fatalf("GetIntegratorFloat() error = %v", err)
+	}
+	if want := liquidity.MustParseMoney("79.75", "USD"); !float.Data.Balance.Equal(want) {
+		t.Errorf("GetIntegratorFloat() balance = %v, want %v", float.Data.Balance, want)
+	}
+
+	txns, err := cl.GetTransaction(cardID, liquidity.Params{})
+	if err != nil {
+		t.Fatalf("GetTransaction() error = %v", err)
+	}
+	if len(txns.Data) != 2 {
+		t.Fatalf("GetTransaction() returned %d transactions, want 2", len(txns.Data))
+	}
+	if got := txns.Data[1]; !got.TransactionBalanceBefore.Equal(liquidity.MustParseMoney("25.50", "USD")) || !got.CardBalanceAfter.Equal(liquidity.MustParseMoney("20.25", "USD")) {
+		t.Errorf("debit balances = %v -> %v, want 25.50 USD -> 20.25 USD", got.TransactionBalanceBefore, got.CardBalanceAfter)
+	}
+}
+
+func TestServer_FloatCurrencyCase(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	cl := srv.Client()
+	srv.SetFloat(liquidity.MustParseMoney("100", "USD"))
+
+	if _, err := cl.GetIntegratorFloat("usd"); err != nil {
+		t.Errorf("GetIntegratorFloat() error = %v", err)
+	}
+
+	floats, err := cl.GetIntegratorFloats([]string{"usd"})
+	if err != nil {
+		t.Fatalf("GetIntegratorFloats() error = %v", err)
+	}
+	if len(floats.Data) != 1 {
+		t.Errorf("GetIntegratorFloats() returned %d floats, want 1", len(floats.Data))
+	}
+}
+
+func TestServer_InsufficientFunds(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	cl := srv.Client()
+	srv.SetFloat(liquidity.MustParseMoney("10", "USD"))
+	cardID := newCard(t, srv, cl)
+
+	_, err := cl.TopUp(cardID, liquidity.MustParseMoney("10.01", "USD"))
+	if !liquidity.IsInsufficientFunds(err) {
+		t.Fatalf("TopUp() error = %v, want insufficient funds", err)
+	}
+
+	_, err = cl.Debit(cardID, liquidity.MustParseMoney("1", "USD"))
+	if !liquidity.IsInsufficientFunds(err) {
+		t.Fatalf("Debit() error = %v, want insufficient funds", err)
+	}
+
+	failed, err := cl.GetFailedTransactions(liquidity.Params{})
+	if err != nil {
+		t.Fatalf("GetFailedTransactions() error = %v", err)
+	}
+	if len(failed.Data) != 2 {
+		t.Errorf("GetFailedTransactions() returned %d transactions, want 2", len(failed.Data))
+	}
+
+	if got := srv.Float("USD"); !got.Equal(liquidity.MustParseMoney("10", "USD")) {
+		t.Errorf("float = %v, want 10.00 USD", got)
+	}
+}
+
+func TestServer_CardStatus(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	cl := srv.Client()
+	srv.SetFloat(liquidity.MustParseMoney("10", "USD"))
+	cardID := newCard(t, srv, cl)
+
+	if _, err := cl.Freeze(cardID); err != nil {
+		t.Fatalf("Freeze() error = %v", err)
+	}
+	if _, err := cl.TopUp(cardID, liquidity.MustParseMoney("1", "USD")); err == nil {
+		t.Error("TopUp() on a frozen card succeeded")
+	}
+	if _, err := cl.Unfreeze(cardID); err != nil {
+		t.Fatalf("Unfreeze() error = %v", err)
+	}
+	if _, err := cl.StopCard(cardID, 1); err != nil {
+		t.Fatalf("StopCard() error = %v", err)
+	}
+	if _, err := cl.Unfreeze(cardID); err == nil {
+		t.Error("Unfreeze() on a stopped card succeeded")
+	}
+
+	card, _ := srv.Card(cardID)
+	if card.Status != "stopped" {
+		t.Errorf("status = %q, want stopped", card.Status)
+	}
+}
+
+func TestServer_Pagination(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	cl := srv.Client()
+	userID := srv.AddUser(liquidity.CreateUserData{FirstName: "Ada", UID: "u-1"})
+	for i := 0; i < 5; i++ {
+		if _, err := cl.CreateCard(liquidity.CreateCardData{UserId: userID, Expiry: "2099-01-01"}); err != nil {
+			t.Fatalf("CreateCard() error = %v", err)
+		}
+	}
+
+	it := cl.CardsIter(liquidity.Params{Limit: 2})
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("CardsIter() error = %v", err)
+	}
+	if n != 5 {
+		t.Errorf("CardsIter() returned %d cards, want 5", n)
+	}
+	if got := srv.Calls(http.MethodGet, "/cards/v1"); got != 3 {
+		t.Errorf("GET /cards/v1 called %d times, want 3", got)
+	}
+}
+
+func TestServer_UserOnboarding(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	cl := srv.Client()
+
+	created, err := cl.CreateUser(liquidity.CreateUserData{FirstName: "Ada", LastName: "Obi", UID: "u-1", KycCountry: "NG"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	userID := created.Data.UserID
+
+	if _, err := cl.CreateUser(liquidity.CreateUserData{FirstName: "Ada", LastName: "Obi", UID: "u-1", KycCountry: "NG"}); err == nil {
+		t.Error("CreateUser() with a duplicate uid succeeded")
+	}
+
+	docs, err := cl.GetCardUserDocURL(userID)
+	if err != nil {
+		t.Fatalf("GetCardUserDocURL() error = %v", err)
+	}
+
+	for _, url := range []string{docs.Data.SelfieUploadURL, docs.Data.IDUploadURL} {
+		req, _ := http.NewRequest(http.MethodPut, url, bytes.NewReader([]byte("image")))
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("upload error = %v", err)
+		}
+		r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			t.Fatalf("upload status = %d, want 200", r.StatusCode)
+		}
+	}
+
+	u, err := cl.GetUser(userID)
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if !u.Data.SelfieUploaded || !u.Data.IDUploaded || !u.Data.OfacChecked || !u.Data.Active {
+		t.Errorf("GetUser() = %+v, want documents uploaded and active", u.Data)
+	}
+}
+
+func TestServer_Unauthorized(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	cl := srv.Client(liquidity.WithAPIKey("wrong"))
+
+	if _, err := cl.GetIntegratorFloats(nil); !liquidity.IsUnauthorized(err) {
+		t.Errorf("GetIntegratorFloats() error = %v, want unauthorized", err)
+	}
+}
+
+func TestServer_Validation(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	_, err := srv.Client().CreateUser(liquidity.CreateUserData{FirstName: "Ada"})
+
+	var apiErr *liquidity.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("CreateUser() error = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || len(apiErr.ValidationIssues) != 3 {
+		t.Errorf("CreateUser() error = %v with %d issues, want 400 with 3", err, len(apiErr.ValidationIssues))
+	}
+}
+
+func TestServer_RetriedTopUpAppliesOnce(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	cl := srv.Client(liquidity.WithRetryPolicy(liquidity.RetryPolicy{MaxAttempts: 2, RetryIdempotent: true}))
+	srv.SetFloat(liquidity.MustParseMoney("100", "USD"))
+	cardID := newCard(t, srv, cl)
+
+	srv.AddRule(Rule{Method: http.MethodPatch, Path: "/card/v1/credit/balance", Nth: []int{1}, Fault: LostResponse()})
+
+	res, err := cl.TopUp(cardID, liquidity.MustParseMoney("10", "USD"), liquidity.IdempotencyKey("topup-1"))
+	if err != nil {
+		t.Fatalf("TopUp() error = %v", err)
+	}
+
+	want := liquidity.MustParseMoney("10", "USD")
+	if card, _ := srv.Card(cardID); !card.Balance.Equal(want) || !res.Data.Balance.Equal(want) {
+		t.Errorf("balance = %v, response %v; want %v", card.Balance, res.Data.Balance, want)
+	}
+	if got := srv.Calls(http.MethodPatch, "/card/v1/credit/balance"); got != 2 {
+		t.Errorf("top-up calls = %d, want 2", got)
+	}
+}
+
+func TestServer_IdempotencyKey(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	// without the client's own store every repeat reaches the server
+	cl := srv.Client(liquidity.WithIdempotencyStore(nil))
+	srv.SetFloat(liquidity.MustParseMoney("100", "USD"))
+	userID := srv.AddUser(liquidity.CreateUserData{FirstName: "Ada", UID: "u-1"})
+
+	data := liquidity.CreateCardData{UserId: userID, Expiry: "2099-01-01"}
+	first, err := cl.CreateCard(data, liquidity.IdempotencyKey("card-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := cl.CreateCard(data, liquidity.IdempotencyKey("card-1"))
+	if err != nil || second.Data.CardId != first.Data.CardId {
+		t.Fatalf("repeated CreateCard() = %v, %v; want card %s", second.Data.CardId, err, first.Data.CardId)
+	}
+	cardID := first.Data.CardId
+
+	ten := liquidity.MustParseMoney("10", "USD")
+	for i := 0; i < 2; i++ {
+		if _, err := cl.TopUp(cardID, ten, liquidity.IdempotencyKey("topup-1")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cl.Debit(cardID, liquidity.MustParseMoney("1", "USD"), liquidity.IdempotencyKey("debit-1")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cl.PostIntegratorDeposit(ten, liquidity.IdempotencyKey("deposit-1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if card, _ := srv.Card(cardID); !card.Balance.Equal(liquidity.MustParseMoney("9", "USD")) {
+		t.Errorf("balance = %v, want 9.00 USD", card.Balance)
+	}
+	if got := len(srv.Transactions(cardID)); got != 2 {
+		t.Errorf("card has %d transactions, want 2", got)
+	}
+	if got := srv.Calls(http.MethodPatch, "/card/v1/credit/balance"); got != 2 {
+		t.Errorf("top-up calls = %d, want both repeats sent", got)
+	}
+
+	var apiErr *liquidity.APIError
+	_, err = cl.TopUp(cardID, liquidity.MustParseMoney("20", "USD"), liquidity.IdempotencyKey("topup-1"))
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("TopUp() with a reused key error = %v, want a 422", err)
+	}
+}
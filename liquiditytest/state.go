@@ -0,0 +1,133 @@
+package liquiditytest
+
+import (
+	"fmt"
+	"strings"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// SetFloat sets the integrator's float balance in the balance's currency,
+// creating the float if needed.
+func (s *Server) SetFloat(balance liquidity.Money) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := s.ensureFloat(balance.Currency)
+	f.Balance = balance
+	f.Balance.Currency = f.Currency
+	f.UpdatedAt = s.timestamp()
+}
+
+// Float returns the integrator's float balance in currency.
+func (s *Server) Float(currency string) liquidity.Money {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, found := s.floats[strings.ToUpper(currency)]; found {
+		return f.Balance
+	}
+
+	return liquidity.NewMoney(0, strings.ToUpper(currency))
+}
+
+// AddUser creates a user directly, bypassing validation, and returns its ID.
+func (s *Server) AddUser(data liquidity.CreateUserData) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addUser(data).ID
+}
+
+// FailOFAC makes the user fail the OFAC check once both KYC documents are
+// uploaded.
+func (s *Server) FailOFAC(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, found := s.users[userID]
+	if !found {
+		return fmt.Errorf("liquiditytest: no user %q", userID)
+	}
+
+	u.failOFAC = true
+
+	return nil
+}
+
+// Card returns the current state of a card, including its balance.
+func (s *Server) Card(cardID string) (liquidity.D2, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, found := s.cards[cardID]
+	if !found {
+		return liquidity.D2{}, false
+	}
+
+	return c.D2, true
+}
+
+// Transactions returns the transactions recorded against a card, oldest
+// first.
+func (s *Server) Transactions(cardID string) []liquidity.D4 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]liquidity.D4(nil), s.txns[cardID]...)
+}
+
+// ConfirmDeposit marks a deposit made with PostIntegratorDeposit as received
+// and credits its amount to the float.
+func (s *Server) ConfirmDeposit(depositID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, found := s.deposits[depositID]
+	if !found {
+		return fmt.Errorf("liquiditytest: no deposit %q", depositID)
+	}
+	if d.U54DepositId != "" {
+		return fmt.Errorf("liquiditytest: deposit %q already confirmed", depositID)
+	}
+
+	f := s.ensureFloat(d.Currency)
+	balance, err := f.Balance.Add(d.Amount)
+	if err != nil {
+		return err
+	}
+
+	f.Balance = balance
+	f.UpdatedAt = s.timestamp()
+	d.U54DepositId = s.newID()
+
+	return nil
+}
+
+// Webhook returns the webhook URL last set by RegisterIntegrator or
+// UpdateWebhook.
+func (s *Server) Webhook() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.webhook
+}
+
+func (s *Server) addUser(data liquidity.CreateUserData) *user {
+	now := s.timestamp()
+	u := &user{
+		ID:         s.newID(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		FirstName:  data.FirstName,
+		LastName:   data.LastName,
+		UID:        data.UID,
+		KycCountry: data.KycCountry,
+		Address:    data.Address,
+		City:       data.City,
+		PostalCode: data.PostalCode,
+	}
+	s.users[u.ID] = u
+
+	return u
+}
@@ -0,0 +1,48 @@
+package liquidity
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger receives the client's debug logs as a message followed by
+// alternating keys and values. *slog.Logger satisfies it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// stdLogger writes to the standard library's default logger.
+type stdLogger struct{}
+
+func (stdLogger) Debug(msg string, args ...interface{}) {
+	log.Print(formatLog(msg, args))
+}
+
+func (stdLogger) Error(msg string, args ...interface{}) {
+	log.Print(formatLog("ERROR "+msg, args))
+}
+
+func formatLog(msg string, args []interface{}) string {
+	var b strings.Builder
+
+	b.WriteString("liquidity: " + msg)
+	for i := 0; i < len(args); i += 2 {
+		b.WriteString(" ")
+		if i+1 == len(args) {
+			b.WriteString(fmt.Sprintf("!BADKEY=%v", args[i]))
+			break
+		}
+		b.WriteString(fmt.Sprintf("%v=%v", args[i], args[i+1]))
+	}
+
+	return b.String()
+}
+
+// nopLogger discards everything.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+
+func (nopLogger) Error(string, ...interface{}) {}
@@ -0,0 +1,368 @@
+package liquidity
+
+import (
+	"bytes"
+	er "errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// defaultPrecision is used for currencies missing from currencyPrecision.
+const defaultPrecision = 2
+
+// currencyPrecision is the number of decimal places of each currency's
+// smallest unit: cents, satoshis, wei and so on.
+var currencyPrecision = map[string]int{
+	"USD":  2,
+	"BTC":  8,
+	"ETH":  18,
+	"USDT": 6,
+	"USDC": 6,
+	"BUSD": 18,
+}
+
+// ErrCurrencyMismatch is returned when combining amounts in different
+// currencies.
+var ErrCurrencyMismatch = er.New("liquidity: currency mismatch")
+
+// Precision returns the number of decimal places used by currency.
+func Precision(currency string) int {
+	if p, ok := currencyPrecision[strings.ToUpper(currency)]; ok {
+		return p
+	}
+
+	return defaultPrecision
+}
+
+// Money is an exact amount of a currency. Amounts are sent to and read from
+// the API as decimal numbers in major units (dollars, bitcoins) without going
+// through float64.
+//
+// The zero value is zero with no currency. Money values are immutable.
+type Money struct {
+	// coef is the unscaled value, so the amount is coef / 10^exp. It is nil
+	// for zero and never has trailing zeros otherwise.
+	coef     *big.Int
+	exp      int
+	Currency string
+}
+
+// NewMoney returns an amount given in the currency's minor units, e.g.
+// NewMoney(1050, "USD") is 10.50 USD.
+func NewMoney(minor int64, currency string) Money {
+	return NewMoneyFromBig(big.NewInt(minor), currency)
+}
+
+// NewMoneyFromBig is like NewMoney for amounts that do not fit in an int64,
+// such as wei.
+func NewMoneyFromBig(minor *big.Int, currency string) Money {
+	return newMoney(new(big.Int).Set(minor), Precision(currency), currency)
+}
+
+// ParseMoney parses a decimal amount in major units, e.g. "10.50". It fails
+// if the amount has more decimal places than the currency allows.
+func ParseMoney(amount string, currency string) (Money, error) {
+	coef, exp, err := parseDecimal(amount)
+	if err != nil {
+		return Money{}, err
+	}
+
+	m := newMoney(coef, exp, currency)
+	if err := m.checkPrecision(); err != nil {
+		return Money{}, err
+	}
+
+	return m, nil
+}
+
+// MustParseMoney is like ParseMoney but panics on error.
+func MustParseMoney(amount string, currency string) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+func newMoney(coef *big.Int, exp int, currency string) Money {
+	m := Money{Currency: currency}
+
+	if coef.Sign() == 0 {
+		return m
+	}
+
+	ten := big.NewInt(10)
+	q, r := new(big.Int), new(big.Int)
+	for exp > 0 {
+		q.QuoRem(coef, ten, r)
+		if r.Sign() != 0 {
+			break
+		}
+		coef.Set(q)
+		exp--
+	}
+
+	m.coef, m.exp = coef, exp
+
+	return m
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.coef == nil
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the amount.
+func (m Money) Sign() int {
+	if m.coef == nil {
+		return 0
+	}
+
+	return m.coef.Sign()
+}
+
+// Cmp compares the amounts of m and o, ignoring their currencies, and returns
+// -1, 0 or +1.
+func (m Money) Cmp(o Money) int {
+	a, b, _ := align(m, o)
+	return a.Cmp(b)
+}
+
+// Equal reports whether m and o have the same amount and currency.
+func (m Money) Equal(o Money) bool {
+	return m.Cmp(o) == 0 && strings.EqualFold(m.Currency, o.Currency)
+}
+
+// Add returns m + o. Both must share a currency, although a value without a
+// currency, such as the zero value, takes on the other's.
+func (m Money) Add(o Money) (Money, error) {
+	currency, err := commonCurrency(m, o)
+	if err != nil {
+		return Money{}, err
+	}
+
+	a, b, exp := align(m, o)
+	return newMoney(a.Add(a, b), exp, currency), nil
+}
+
+// Sub returns m - o under the same currency rules as Add.
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(o.Neg())
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	if m.coef == nil {
+		return m
+	}
+
+	return Money{coef: new(big.Int).Neg(m.coef), exp: m.exp, Currency: m.Currency}
+}
+
+// Abs returns the absolute value of m.
+func (m Money) Abs() Money {
+	if m.Sign() < 0 {
+		return m.Neg()
+	}
+
+	return m
+}
+
+// MinorUnits returns the amount in the currency's minor units. It fails if
+// the amount is finer than the currency's precision.
+func (m Money) MinorUnits() (*big.Int, error) {
+	if err := m.checkPrecision(); err != nil {
+		return nil, err
+	}
+
+	return m.scaled(Precision(m.Currency)), nil
+}
+
+// Amount formats the amount in major units with the currency's number of
+// decimal places, e.g. "10.50".
+func (m Money) Amount() string {
+	p := Precision(m.Currency)
+	if m.exp > p {
+		p = m.exp
+	}
+
+	return formatDecimal(m.scaled(p), p)
+}
+
+// String formats the amount followed by its currency, e.g. "10.50 USD".
+func (m Money) String() string {
+	if m.Currency == "" {
+		return m.Amount()
+	}
+
+	return m.Amount() + " " + strings.ToUpper(m.Currency)
+}
+
+// MarshalJSON encodes the amount as a JSON number in major units.
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m.coef == nil {
+		return []byte("0"), nil
+	}
+
+	return []byte(formatDecimal(m.coef, m.exp)), nil
+}
+
+// UnmarshalJSON decodes a JSON number or numeric string in major units. The
+// currency is left as is; response types fill it in from their currency
+// field.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	s := string(data)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+
+	coef, exp, err := parseDecimal(s)
+	if err != nil {
+		return err
+	}
+
+	*m = newMoney(coef, exp, m.Currency)
+
+	return nil
+}
+
+// checkAmount validates an amount about to be sent to the API.
+func checkAmount(m Money, needCurrency bool) error {
+	if m.Sign() <= 0 {
+		return fmt.Errorf("liquidity: amount must be positive, got %s", m)
+	}
+
+	if needCurrency && m.Currency == "" {
+		return er.New("liquidity: amount has no currency")
+	}
+
+	return m.checkPrecision()
+}
+
+func (m Money) checkPrecision() error {
+	if p := Precision(m.Currency); m.exp > p {
+		return fmt.Errorf("liquidity: %s has more than %d decimal places for %s", formatDecimal(m.coef, m.exp), p, m.Currency)
+	}
+
+	return nil
+}
+
+// scaled returns the amount multiplied by 10^exp, which must be at least
+// m.exp.
+func (m Money) scaled(exp int) *big.Int {
+	if m.coef == nil {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Mul(m.coef, pow10(exp-m.exp))
+}
+
+// align returns the unscaled values of a and b at a common exponent.
+func align(a, b Money) (*big.Int, *big.Int, int) {
+	exp := a.exp
+	if b.exp > exp {
+		exp = b.exp
+	}
+
+	return a.scaled(exp), b.scaled(exp), exp
+}
+
+func commonCurrency(a, b Money) (string, error) {
+	switch {
+	case a.Currency == "":
+		return b.Currency, nil
+	case b.Currency == "", strings.EqualFold(a.Currency, b.Currency):
+		return a.Currency, nil
+	}
+
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, a.Currency, b.Currency)
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// maxExponent bounds the exponent accepted by parseDecimal so a hostile
+// value cannot allocate a huge number.
+const maxExponent = 100
+
+// parseDecimal parses a decimal number such as "-12.50" or "1.5e3" into an
+// unscaled value and the number of decimal places.
+func parseDecimal(s string) (*big.Int, int, error) {
+	invalid := fmt.Errorf("liquidity: invalid amount %q", s)
+
+	mantissa, exponent := s, ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exponent = s[:i], s[i+1:]
+	}
+
+	neg := false
+	if strings.HasPrefix(mantissa, "-") || strings.HasPrefix(mantissa, "+") {
+		neg = mantissa[0] == '-'
+		mantissa = mantissa[1:]
+	}
+
+	whole, frac := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		whole, frac = mantissa[:i], mantissa[i+1:]
+	}
+
+	digits := whole + frac
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return nil, 0, invalid
+	}
+
+	exp := len(frac)
+	if exponent != "" {
+		e, err := strconv.Atoi(exponent)
+		if err != nil || e > maxExponent || e < -maxExponent {
+			return nil, 0, invalid
+		}
+		exp -= e
+	}
+
+	coef, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, 0, invalid
+	}
+
+	if exp < 0 {
+		coef.Mul(coef, pow10(-exp))
+		exp = 0
+	}
+
+	if neg {
+		coef.Neg(coef)
+	}
+
+	return coef, exp, nil
+}
+
+// formatDecimal formats coef / 10^exp with exactly exp decimal places.
+func formatDecimal(coef *big.Int, exp int) string {
+	if coef == nil {
+		coef = new(big.Int)
+	}
+
+	digits := new(big.Int).Abs(coef).String()
+	if exp > 0 {
+		if len(digits) <= exp {
+			digits = strings.Repeat("0", exp-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+	}
+
+	if coef.Sign() < 0 {
+		digits = "-" + digits
+	}
+
+	return digits
+}
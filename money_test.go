@@ -0,0 +1,141 @@
+package liquidity
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     string
+		currency   string
+		wantString string
+		wantMinor  string
+		wantErr    bool
+	}{
+		{name: "dollars and cents", amount: "10.5", currency: "USD", wantString: "10.50 USD", wantMinor: "1050"},
+		{name: "whole dollars", amount: "1000", currency: "USD", wantString: "1000.00 USD", wantMinor: "100000"},
+		{name: "satoshis", amount: "0.00000001", currency: "BTC", wantString: "0.00000001 BTC", wantMinor: "1"},
+		{name: "wei", amount: "123456789.123456789123456789", currency: "ETH", wantString: "123456789.123456789123456789 ETH", wantMinor: "123456789123456789123456789"},
+		{name: "negative", amount: "-0.1", currency: "USD", wantString: "-0.10 USD", wantMinor: "-10"},
+		{name: "exponent", amount: "1.5e2", currency: "USD", wantString: "150.00 USD", wantMinor: "15000"},
+		{name: "unknown currency uses two places", amount: "3.1", currency: "XYZ", wantString: "3.10 XYZ", wantMinor: "310"},
+		{name: "too precise for the currency", amount: "0.001", currency: "USD", wantErr: true},
+		{name: "not a number", amount: "ten", currency: "USD", wantErr: true},
+		{name: "empty", amount: "", currency: "USD", wantErr: true},
+		{name: "huge exponent", amount: "1e1000000", currency: "USD", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMoney(tt.amount, tt.currency)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMoney() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := m.String(); got != tt.wantString {
+				t.Errorf("String() = %q, want %q", got, tt.wantString)
+			}
+			minor, err := m.MinorUnits()
+			if err != nil || minor.String() != tt.wantMinor {
+				t.Errorf("MinorUnits() = %v, %v, want %s", minor, err, tt.wantMinor)
+			}
+		})
+	}
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a := NewMoney(1050, "USD")
+	b := MustParseMoney("0.55", "USD")
+
+	sum, err := a.Add(b)
+	if err != nil || !sum.Equal(NewMoney(1105, "USD")) {
+		t.Errorf("Add() = %v, %v, want 11.05 USD", sum, err)
+	}
+
+	diff, err := b.Sub(a)
+	if err != nil || !diff.Equal(MustParseMoney("-9.95", "USD")) {
+		t.Errorf("Sub() = %v, %v, want -9.95 USD", diff, err)
+	}
+
+	total, err := Money{}.Add(a)
+	if err != nil || !total.Equal(a) {
+		t.Errorf("Add() on zero value = %v, %v, want %v", total, err, a)
+	}
+
+	if _, err := a.Add(NewMoney(1, "BTC")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add() error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+
+	if a.Cmp(b) != 1 || b.Cmp(a) != -1 || a.Cmp(MustParseMoney("10.50", "USD")) != 0 {
+		t.Errorf("Cmp() gave an inconsistent ordering")
+	}
+
+	wei, _ := new(big.Int).SetString("1000000000000000001", 10)
+	eth := NewMoneyFromBig(wei, "ETH")
+	if got := eth.Amount(); got != "1.000000000000000001" {
+		t.Errorf("Amount() = %q, want 1.000000000000000001", got)
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	var d D2
+	if err := json.Unmarshal([]byte(`{"balance":"12.34","currency":"USD"}`), &d); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !d.Balance.Equal(NewMoney(1234, "USD")) {
+		t.Errorf("Balance = %v, want 12.34 USD", d.Balance)
+	}
+
+	var f D6
+	if err := json.Unmarshal([]byte(`{"balance":0.1,"currency":"BTC"}`), &f); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !f.Balance.Equal(NewMoney(10000000, "BTC")) {
+		t.Errorf("Balance = %v, want 0.10000000 BTC", f.Balance)
+	}
+
+	b, err := json.Marshal(amountBody{Amount: MustParseMoney("0.30", "USD")})
+	if err != nil || string(b) != `{"amount":0.3}` {
+		t.Errorf("Marshal() = %s, %v, want {\"amount\":0.3}", b, err)
+	}
+}
+
+type amountBody struct {
+	Amount Money `json:"amount"`
+}
+
+func TestClient_TopUpAmount(t *testing.T) {
+	var body string
+	c := newTestClient(&MockHttpClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			b, _ := ioutil.ReadAll(r.Body)
+			body = string(b)
+			return jsonResponse(200, `{"message":"Ok"}`), nil
+		},
+	})
+
+	if _, err := c.TopUp("c954e4c9", MustParseMoney("0.1", "USD")); err != nil {
+		t.Fatalf("TopUp() error = %v", err)
+	}
+	if want := `{"cardId":"c954e4c9","amount":0.1}`; body != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+
+	body = ""
+	if _, err := c.Debit("c954e4c9", NewMoney(-100, "USD")); err == nil {
+		t.Errorf("Debit() with a negative amount should fail")
+	}
+	if _, err := c.PostIntegratorDeposit(NewMoney(100, "")); err == nil {
+		t.Errorf("PostIntegratorDeposit() without a currency should fail")
+	}
+	if body != "" {
+		t.Errorf("invalid amounts were sent: %s", body)
+	}
+}
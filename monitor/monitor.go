@@ -0,0 +1,268 @@
+// Package monitor watches the integrator's float balances and raises alerts
+// when one runs low.
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// DefaultInterval is how often Run polls when Monitor.Interval is zero.
+const DefaultInterval = 5 * time.Minute
+
+// FloatSource reads float balances. *liquidity.Client satisfies it.
+type FloatSource interface {
+	GetIntegratorFloatsContext(ctx context.Context, currencies []string) (liquidity.FloatsResp, error)
+}
+
+// Threshold is the alerting band of one currency. An alert is raised when
+// the balance falls below Low, and no other until it has climbed back to
+// Recover, so a balance hovering around Low does not raise an alert on every
+// poll.
+type Threshold struct {
+	Low liquidity.Money
+	// Recover is the balance that ends a low period. Below Low, it is
+	// treated as Low.
+	Recover liquidity.Money
+}
+
+// AlertKind tells whether a float went low or recovered.
+type AlertKind string
+
+const (
+	AlertLow       AlertKind = "low"
+	AlertRecovered AlertKind = "recovered"
+)
+
+// Alert reports a float crossing its threshold.
+type Alert struct {
+	Kind      AlertKind       `json:"kind"`
+	Currency  string          `json:"currency"`
+	FloatID   string          `json:"floatId"`
+	Balance   liquidity.Money `json:"balance"`
+	Threshold liquidity.Money `json:"threshold"`
+	Time      time.Time       `json:"time"`
+}
+
+func (a Alert) String() string {
+	if a.Kind == AlertRecovered {
+		return fmt.Sprintf("%s float recovered to %s", a.Currency, a.Balance)
+	}
+
+	return fmt.Sprintf("%s float is low: %s, below %s", a.Currency, a.Balance, a.Threshold)
+}
+
+// Monitor polls float balances and sends alerts to its notifiers. The zero
+// value is ready to use once Source is set.
+type Monitor struct {
+	Source    FloatSource
+	Notifiers []Notifier
+
+	// Interval is the time between polls. Zero means DefaultInterval.
+	Interval time.Duration
+	// OnError receives failed polls and notifications during Run. It
+	// defaults to discarding them.
+	OnError func(err error)
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+
+	mu         sync.Mutex
+	thresholds map[string]Threshold
+	low        map[string]bool
+	pending    map[string]*delivery
+}
+
+// New returns a Monitor reading from src and alerting notifiers.
+func New(src FloatSource, notifiers ...Notifier) *Monitor {
+	return &Monitor{Source: src, Notifiers: notifiers}
+}
+
+// SetThreshold watches currency with the given band. Setting a threshold
+// resets the currency's state, so a float that is already low alerts again.
+func (m *Monitor) SetThreshold(currency string, t Threshold) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.thresholds == nil {
+		m.thresholds = make(map[string]Threshold)
+	}
+
+	currency = strings.ToUpper(currency)
+	m.thresholds[currency] = t
+	delete(m.low, currency)
+	delete(m.pending, currency)
+}
+
+// RemoveThreshold stops watching currency.
+func (m *Monitor) RemoveThreshold(currency string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	currency = strings.ToUpper(currency)
+	delete(m.thresholds, currency)
+	delete(m.low, currency)
+	delete(m.pending, currency)
+}
+
+// Check polls once and notifies about every threshold crossed since the last
+// poll. It returns the alerts sent and the first error from the source or a
+// notifier. A notifier that fails to deliver an alert gets it again on the
+// next poll; the notifiers that accepted it do not.
+func (m *Monitor) Check(ctx context.Context) ([]Alert, error) {
+	res, err := m.Source.GetIntegratorFloatsContext(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("monitor: reading floats: %w", err)
+	}
+
+	var alerts []Alert
+	var firstErr error
+	for _, s := range m.evaluate(res.Data) {
+		alerts = append(alerts, s.alert)
+
+		var failed []int
+		for _, i := range s.to {
+			if i >= len(m.Notifiers) {
+				continue
+			}
+			if err := m.Notifiers[i].Notify(ctx, s.alert); err != nil {
+				failed = append(failed, i)
+				if firstErr == nil {
+					firstErr = fmt.Errorf("monitor: notifying %s alert for %s: %w", s.alert.Kind, s.alert.Currency, err)
+				}
+			}
+		}
+
+		m.delivered(s.d, failed)
+	}
+
+	return alerts, firstErr
+}
+
+// delivery is the latest alert of a currency and the notifiers, by index in
+// Notifiers, that have yet to accept it.
+type delivery struct {
+	alert Alert
+	to    []int
+}
+
+// send is a delivery to attempt, copied out of the lock.
+type send struct {
+	d     *delivery
+	alert Alert
+	to    []int
+}
+
+// evaluate updates the low state of each watched float, queues an alert for
+// every notifier when it changes, and returns the deliveries to attempt. A
+// new alert for a currency replaces one still pending.
+func (m *Monitor) evaluate(floats []liquidity.D6) []send {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now
+	if m.Now != nil {
+		now = m.Now
+	}
+
+	if m.low == nil {
+		m.low = make(map[string]bool)
+	}
+	if m.pending == nil {
+		m.pending = make(map[string]*delivery)
+	}
+
+	for _, f := range floats {
+		currency := strings.ToUpper(f.Currency)
+
+		t, watched := m.thresholds[currency]
+		if !watched {
+			continue
+		}
+
+		recovery := t.Recover
+		if recovery.Cmp(t.Low) < 0 {
+			recovery = t.Low
+		}
+
+		a := Alert{Currency: currency, FloatID: f.FloatId, Balance: f.Balance, Time: now()}
+
+		switch {
+		case !m.low[currency] && f.Balance.Cmp(t.Low) < 0:
+			a.Kind, a.Threshold = AlertLow, t.Low
+		case m.low[currency] && f.Balance.Cmp(recovery) >= 0:
+			a.Kind, a.Threshold = AlertRecovered, recovery
+		default:
+			continue
+		}
+
+		m.low[currency] = a.Kind == AlertLow
+
+		to := make([]int, len(m.Notifiers))
+		for i := range to {
+			to[i] = i
+		}
+		m.pending[currency] = &delivery{alert: a, to: to}
+	}
+
+	currencies := make([]string, 0, len(m.pending))
+	for c := range m.pending {
+		currencies = append(currencies, c)
+	}
+	sort.Strings(currencies)
+
+	sends := make([]send, 0, len(currencies))
+	for _, c := range currencies {
+		d := m.pending[c]
+		sends = append(sends, send{d: d, alert: d.alert, to: append([]int(nil), d.to...)})
+	}
+
+	return sends
+}
+
+// delivered leaves only the failed notifiers pending on d, unless a newer
+// alert has replaced it.
+func (m *Monitor) delivered(d *delivery, failed []int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	currency := d.alert.Currency
+	if m.pending[currency] != d {
+		return
+	}
+
+	if len(failed) == 0 {
+		delete(m.pending, currency)
+		return
+	}
+	d.to = failed
+}
+
+// Run checks immediately and then every Interval until ctx is done, which it
+// returns.
+func (m *Monitor) Run(ctx context.Context) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Check(ctx); err != nil && m.OnError != nil && ctx.Err() == nil {
+			m.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// balances returns one USD float balance per poll.
+type balances []string
+
+func (b *balances) GetIntegratorFloatsContext(ctx context.Context, currencies []string) (liquidity.FloatsResp, error) {
+	if len(*b) == 0 {
+		return liquidity.FloatsResp{}, errors.New("no more balances")
+	}
+
+	next := (*b)[0]
+	*b = (*b)[1:]
+
+	return liquidity.FloatsResp{Data: []liquidity.D6{
+		{FloatId: "f-usd", Currency: "USD", Balance: liquidity.MustParseMoney(next, "USD")},
+		{FloatId: "f-btc", Currency: "BTC", Balance: liquidity.NewMoney(0, "BTC")},
+	}}, nil
+}
+
+func TestMonitor_Hysteresis(t *testing.T) {
+	src := balances{"500", "90", "80", "105", "95", "150", "120", "40"}
+	want := []AlertKind{"", AlertLow, "", "", "", AlertRecovered, "", AlertLow}
+
+	var got []Alert
+	m := New(&src, NotifierFunc(func(ctx context.Context, a Alert) error {
+		got = append(got, a)
+		return nil
+	}))
+	m.SetThreshold("usd", Threshold{Low: liquidity.MustParseMoney("100", "USD"), Recover: liquidity.MustParseMoney("150", "USD")})
+
+	for i, kind := range want {
+		alerts, err := m.Check(context.Background())
+		if err != nil {
+			t.Fatalf("poll %d: Check() error = %v", i+1, err)
+		}
+
+		switch {
+		case kind == "" && len(alerts) != 0:
+			t.Errorf("poll %d: alerts = %v, want none", i+1, alerts)
+		case kind != "" && (len(alerts) != 1 || alerts[0].Kind != kind):
+			t.Errorf("poll %d: alerts = %v, want %s", i+1, alerts, kind)
+		}
+	}
+
+	if len(got) != 3 || got[0].Currency != "USD" || got[0].FloatID != "f-usd" {
+		t.Errorf("notified %v, want 3 USD alerts", got)
+	}
+}
+
+func TestMonitor_NotifierErrorStillAlerts(t *testing.T) {
+	src := balances{"1"}
+
+	ch := make(chan Alert, 1)
+	m := New(&src,
+		NotifierFunc(func(ctx context.Context, a Alert) error { return errors.New("down") }),
+		ChanNotifier(ch),
+	)
+	m.SetThreshold("USD", Threshold{Low: liquidity.MustParseMoney("10", "USD")})
+
+	alerts, err := m.Check(context.Background())
+	if err == nil || len(alerts) != 1 {
+		t.Fatalf("Check() = %v, %v; want 1 alert and an error", alerts, err)
+	}
+
+	select {
+	case a := <-ch:
+		if a.Kind != AlertLow {
+			t.Errorf("channel got %v", a)
+		}
+	default:
+		t.Error("channel notifier was skipped")
+	}
+}
+
+func TestMonitor_RetriesOnlyFailedNotifiers(t *testing.T) {
+	src := balances{"1", "2", "3"}
+
+	failures := 1
+	var flaky, working []Alert
+	m := New(&src,
+		NotifierFunc(func(ctx context.Context, a Alert) error {
+			flaky = append(flaky, a)
+			if failures > 0 {
+				failures--
+				return errors.New("down")
+			}
+			return nil
+		}),
+		NotifierFunc(func(ctx context.Context, a Alert) error {
+			working = append(working, a)
+			return nil
+		}),
+	)
+	m.SetThreshold("USD", Threshold{Low: liquidity.MustParseMoney("10", "USD")})
+
+	for i := 0; i < 3; i++ {
+		m.Check(context.Background())
+	}
+
+	if len(flaky) != 2 || len(working) != 1 {
+		t.Errorf("flaky notifier got %d alerts, working one %d; want 2 and 1", len(flaky), len(working))
+	}
+}
+
+func TestLogNotifier_NoLogger(t *testing.T) {
+	a := Alert{Kind: AlertLow, Currency: "USD", Balance: liquidity.MustParseMoney("5", "USD"), Threshold: liquidity.MustParseMoney("10", "USD")}
+	if err := (LogNotifier{}).Notify(context.Background(), a); err != nil {
+		t.Errorf("Notify() error = %v", err)
+	}
+}
+
+func TestMonitor_FailedAlertResent(t *testing.T) {
+	src := balances{"1", "2", "3"}
+
+	fail := true
+	var got []Alert
+	m := &Monitor{Source: &src, Notifiers: []Notifier{NotifierFunc(func(ctx context.Context, a Alert) error {
+		got = append(got, a)
+		if fail {
+			fail = false
+			return errors.New("down")
+		}
+		return nil
+	})}}
+	m.SetThreshold("USD", Threshold{Low: liquidity.MustParseMoney("10", "USD")})
+
+	want := []struct {
+		alerts  int
+		wantErr bool
+	}{
+		{alerts: 1, wantErr: true},
+		{alerts: 1},
+		{alerts: 0},
+	}
+	for i, w := range want {
+		alerts, err := m.Check(context.Background())
+		if len(alerts) != w.alerts || (err != nil) != w.wantErr {
+			t.Errorf("poll %d: Check() = %v, %v; want %d alerts, error %v", i+1, alerts, err, w.alerts, w.wantErr)
+		}
+	}
+
+	if len(got) != 2 || got[1].Kind != AlertLow {
+		t.Errorf("notified %v, want the failed low alert sent again", got)
+	}
+}
+
+func TestMonitor_ZeroValue(t *testing.T) {
+	var m Monitor
+	m.RemoveThreshold("USD")
+	m.SetThreshold("USD", Threshold{Low: liquidity.MustParseMoney("10", "USD")})
+
+	src := balances{"1"}
+	m.Source = &src
+
+	alerts, err := m.Check(context.Background())
+	if err != nil || len(alerts) != 1 {
+		t.Errorf("Check() = %v, %v; want 1 alert", alerts, err)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+
+	a := Alert{Kind: AlertLow, Currency: "USD", Balance: liquidity.MustParseMoney("5", "USD"), Threshold: liquidity.MustParseMoney("10", "USD")}
+
+	n := WebhookNotifier{URL: srv.URL, Header: http.Header{"X-Token": {"secret"}}}
+	if err := n.Notify(context.Background(), a); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if body["kind"] != "low" || body["balance"] != 5.0 || body["text"] != "USD float is low: 5.00 USD, below 10.00 USD" {
+		t.Errorf("posted %v", body)
+	}
+
+	n.Header = nil
+	if err := n.Notify(context.Background(), a); err == nil {
+		t.Error("Notify() with a rejected request succeeded")
+	}
+}
+
+func TestMonitor_Run(t *testing.T) {
+	src := balances{"500", "5"}
+
+	ch := make(chan Alert)
+	var errs []error
+	m := New(&src, ChanNotifier(ch))
+	m.Interval = time.Millisecond
+	m.OnError = func(err error) { errs = append(errs, err) }
+	m.SetThreshold("USD", Threshold{Low: liquidity.MustParseMoney("10", "USD")})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	if a := <-ch; a.Kind != AlertLow {
+		t.Errorf("Run() alerted %v", a)
+	}
+
+	// Let a failing poll happen before stopping.
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() = %v, want context.Canceled", err)
+	}
+	if len(errs) == 0 {
+		t.Error("OnError was not called for failed polls")
+	}
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// Notifier delivers alerts.
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// NotifierFunc adapts a function to Notifier.
+type NotifierFunc func(ctx context.Context, a Alert) error
+
+func (f NotifierFunc) Notify(ctx context.Context, a Alert) error {
+	return f(ctx, a)
+}
+
+// LogNotifier writes alerts to a logger, low floats at error level.
+type LogNotifier struct {
+	// Logger defaults to the standard library's log package.
+	Logger liquidity.Logger
+}
+
+func (n LogNotifier) Notify(ctx context.Context, a Alert) error {
+	if n.Logger == nil {
+		log.Print("monitor: " + a.String())
+		return nil
+	}
+
+	args := []interface{}{"currency", a.Currency, "balance", a.Balance.String(), "threshold", a.Threshold.String()}
+
+	if a.Kind == AlertLow {
+		n.Logger.Error("float low", args...)
+	} else {
+		n.Logger.Debug("float recovered", args...)
+	}
+
+	return nil
+}
+
+// ChanNotifier sends alerts on a channel. It blocks until the alert is
+// received or ctx is done.
+type ChanNotifier chan<- Alert
+
+func (n ChanNotifier) Notify(ctx context.Context, a Alert) error {
+	select {
+	case n <- a:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WebhookNotifier POSTs each alert as JSON to a URL, e.g. a chat webhook or
+// an incident tool.
+type WebhookNotifier struct {
+	URL string
+	// Client sends the requests. It defaults to http.DefaultClient.
+	Client liquidity.HTTPClient
+	// Header is added to every request, e.g. for authentication.
+	Header http.Header
+}
+
+func (n WebhookNotifier) Notify(ctx context.Context, a Alert) error {
+	body, err := json.Marshal(struct {
+		Alert
+		Text string `json:"text"`
+	}{a, a.String()})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for k, v := range n.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	r, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	io.Copy(io.Discard, r.Body)
+
+	if r.StatusCode < 200 || r.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", r.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+// Package onboard drives the KYC onboarding of card users: creating the
+// user, handing out the document upload URLs and waiting for One Liquidity
+// to check the documents and activate the user. Progress is saved after
+// every step, so an onboarding interrupted by a restart carries on where it
+// stopped.
+package onboard
+
+import (
+	"context"
+	er "errors"
+	"fmt"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// DefaultPollInterval is how often Wait checks the user when
+// Onboarder.PollInterval is zero.
+const DefaultPollInterval = 10 * time.Second
+
+// Stage is how far an onboarding has got.
+type Stage string
+
+const (
+	// Created means the user exists but has no upload URLs yet.
+	Created Stage = "created"
+	// AwaitingDocuments means the upload URLs were issued and the selfie or
+	// ID is still missing.
+	AwaitingDocuments Stage = "awaiting_documents"
+	// Verifying means both documents are in and the user is not active yet.
+	Verifying Stage = "verifying"
+	// Active means the user can be issued cards.
+	Active Stage = "active"
+	// Failed means the user failed the OFAC check. It is final.
+	Failed Stage = "failed"
+)
+
+// Errors returned by the Onboarder.
+var (
+	ErrOFACFailed = er.New("onboard: user failed the OFAC check")
+	ErrNotStarted = er.New("onboard: onboarding not started")
+)
+
+// Client is the part of *liquidity.Client the Onboarder uses.
+type Client interface {
+	CreateUserContext(ctx context.Context, userData liquidity.CreateUserData) (liquidity.CreateUserResp, error)
+	GetCardUserDocURLContext(ctx context.Context, userID string) (liquidity.CardUserDocURLResp, error)
+	GetUserContext(ctx context.Context, userID string) (liquidity.UserResp, error)
+}
+
+// State is the saved progress of one onboarding, keyed by the integrator's
+// UID for the user.
+type State struct {
+	UID    string
+	UserID string
+	Stage  Stage
+
+	SelfieUploadURL string
+	IDUploadURL     string
+
+	SelfieUploaded bool
+	IDUploaded     bool
+	OfacChecked    bool
+
+	UpdatedAt time.Time
+}
+
+// Onboarder runs onboardings.
+type Onboarder struct {
+	Client Client
+	Store  Store
+
+	// PollInterval is the time between checks in Wait. Zero means
+	// DefaultPollInterval.
+	PollInterval time.Duration
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// New returns an Onboarder that calls client and saves to store.
+func New(client Client, store Store) *Onboarder {
+	return &Onboarder{Client: client, Store: store}
+}
+
+// Start creates the user described by data, unless an earlier run already
+// did, and fetches fresh document upload URLs. It returns the state with the
+// URLs to hand to the user. Starting an onboarding that is past the upload
+// stage returns its state unchanged, or ErrOFACFailed if it failed.
+//
+// The state is saved as soon as CreateUser returns. If the process stops
+// before that, the user exists but the Store does not know it, and Start
+// creates another: the API cannot look users up by UID.
+func (o *Onboarder) Start(ctx context.Context, data liquidity.CreateUserData) (State, error) {
+	if data.UID == "" {
+		return State{}, er.New("onboard: user has no UID")
+	}
+
+	s, found, err := o.Store.Load(ctx, data.UID)
+	if err != nil {
+		return s, err
+	}
+
+	if !found {
+		res, err := o.Client.CreateUserContext(ctx, data)
+		if err != nil {
+			return s, fmt.Errorf("onboard: creating user %s: %w", data.UID, err)
+		}
+
+		s = State{UID: data.UID, UserID: res.Data.UserID, Stage: Created}
+		if err := o.save(ctx, &s); err != nil {
+			return s, err
+		}
+	}
+
+	switch s.Stage {
+	case Created, AwaitingDocuments:
+		return o.fetchURLs(ctx, s)
+	case Failed:
+		return s, o.failed(s)
+	}
+
+	return s, nil
+}
+
+func (o *Onboarder) fetchURLs(ctx context.Context, s State) (State, error) {
+	res, err := o.Client.GetCardUserDocURLContext(ctx, s.UserID)
+	if err != nil {
+		return s, fmt.Errorf("onboard: fetching upload URLs for %s: %w", s.UID, err)
+	}
+
+	s.SelfieUploadURL = res.Data.SelfieUploadURL
+	s.IDUploadURL = res.Data.IDUploadURL
+	s.Stage = AwaitingDocuments
+
+	return s, o.save(ctx, &s)
+}
+
+// State returns the saved state for uid.
+func (o *Onboarder) State(ctx context.Context, uid string) (State, error) {
+	s, found, err := o.Store.Load(ctx, uid)
+	if err == nil && !found {
+		err = ErrNotStarted
+	}
+
+	return s, err
+}
+
+// Stage returns the saved stage for uid.
+func (o *Onboarder) Stage(ctx context.Context, uid string) (Stage, error) {
+	s, err := o.State(ctx, uid)
+	return s.Stage, err
+}
+
+// Refresh checks the user once and moves the onboarding to the stage its
+// KYC flags show. It returns ErrOFACFailed once the user failed the check.
+func (o *Onboarder) Refresh(ctx context.Context, uid string) (State, error) {
+	s, err := o.State(ctx, uid)
+	if err != nil {
+		return s, err
+	}
+
+	switch s.Stage {
+	case Active:
+		return s, nil
+	case Failed:
+		return s, o.failed(s)
+	}
+
+	res, err := o.Client.GetUserContext(ctx, s.UserID)
+	if err != nil {
+		return s, fmt.Errorf("onboard: checking user %s: %w", uid, err)
+	}
+
+	u := res.Data
+	s.SelfieUploaded, s.IDUploaded, s.OfacChecked = u.SelfieUploaded, u.IDUploaded, u.OfacChecked
+
+	switch {
+	case u.OfacFail:
+		s.Stage = Failed
+	case u.Active:
+		s.Stage = Active
+	case u.DocumentsUploaded():
+		s.Stage = Verifying
+	}
+
+	if err := o.save(ctx, &s); err != nil {
+		return s, err
+	}
+
+	if s.Stage == Failed {
+		return s, o.failed(s)
+	}
+
+	return s, nil
+}
+
+// Wait refreshes the onboarding every PollInterval until the user is active
+// or has failed, or ctx is done.
+func (o *Onboarder) Wait(ctx context.Context, uid string) (State, error) {
+	interval := o.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s, err := o.Refresh(ctx, uid)
+		if err != nil || s.Stage == Active {
+			return s, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return s, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *Onboarder) save(ctx context.Context, s *State) error {
+	now := time.Now
+	if o.Now != nil {
+		now = o.Now
+	}
+	s.UpdatedAt = now()
+
+	if err := o.Store.Save(ctx, *s); err != nil {
+		return fmt.Errorf("onboard: saving %s: %w", s.UID, err)
+	}
+
+	return nil
+}
+
+func (o *Onboarder) failed(s State) error {
+	return fmt.Errorf("%w: user %s (%s)", ErrOFACFailed, s.UID, s.UserID)
+}
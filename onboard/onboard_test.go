@@ -0,0 +1,137 @@
+package onboard
+
+import (
+	"context"
+	er "errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+	"github.com/bushaHQ/one-liquidity-go/liquiditytest"
+)
+
+var ada = liquidity.CreateUserData{
+	FirstName:  "Ada",
+	LastName:   "Lovelace",
+	KycCountry: "NG",
+	UID:        "u-1",
+	Address:    "1 Marina",
+	City:       "Lagos",
+	PostalCode: "100001",
+}
+
+func upload(t *testing.T, url string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader("image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		t.Fatalf("upload status = %d", r.StatusCode)
+	}
+}
+
+func TestOnboarder(t *testing.T) {
+	srv := liquiditytest.NewServer()
+	defer srv.Close()
+
+	store := NewMemoryStore()
+	o := New(srv.Client(), store)
+	ctx := context.Background()
+
+	if _, err := o.Stage(ctx, ada.UID); err != ErrNotStarted {
+		t.Fatalf("Stage() error = %v, want ErrNotStarted", err)
+	}
+
+	s, err := o.Start(ctx, ada)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Stage != AwaitingDocuments || s.UserID == "" || s.SelfieUploadURL == "" || s.IDUploadURL == "" {
+		t.Fatalf("Start() = %+v, want upload URLs", s)
+	}
+
+	upload(t, s.SelfieUploadURL)
+
+	if s, err = o.Refresh(ctx, ada.UID); err != nil || s.Stage != AwaitingDocuments || !s.SelfieUploaded {
+		t.Fatalf("Refresh() = %+v, %v; want the selfie uploaded", s, err)
+	}
+
+	// a restarted process resumes without creating the user again
+	o = New(srv.Client(), store)
+	o.PollInterval = time.Millisecond
+
+	if s, err = o.Start(ctx, ada); err != nil || s.Stage != AwaitingDocuments {
+		t.Fatalf("Start() = %+v, %v; want to resume", s, err)
+	}
+	if got := srv.Calls(http.MethodPost, "/card/v1/user"); got != 1 {
+		t.Errorf("created the user %d times, want 1", got)
+	}
+
+	upload(t, s.IDUploadURL)
+
+	s, err = o.Wait(ctx, ada.UID)
+	if err != nil || s.Stage != Active || !s.IDUploaded || !s.OfacChecked {
+		t.Fatalf("Wait() = %+v, %v; want active", s, err)
+	}
+
+	if stage, _ := o.Stage(ctx, ada.UID); stage != Active {
+		t.Errorf("Stage() = %s, want %s", stage, Active)
+	}
+}
+
+func TestOnboarder_OFACFail(t *testing.T) {
+	srv := liquiditytest.NewServer()
+	defer srv.Close()
+
+	o := New(srv.Client(), NewMemoryStore())
+	ctx := context.Background()
+
+	s, err := o.Start(ctx, ada)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.FailOFAC(s.UserID)
+
+	upload(t, s.SelfieUploadURL)
+	upload(t, s.IDUploadURL)
+
+	s, err = o.Wait(ctx, ada.UID)
+	if !er.Is(err, ErrOFACFailed) || s.Stage != Failed {
+		t.Fatalf("Wait() = %+v, %v; want ErrOFACFailed", s, err)
+	}
+
+	if _, err := o.Start(ctx, ada); !er.Is(err, ErrOFACFailed) {
+		t.Errorf("Start() error = %v, want ErrOFACFailed", err)
+	}
+}
+
+func TestOnboarder_WaitTimesOut(t *testing.T) {
+	srv := liquiditytest.NewServer()
+	defer srv.Close()
+
+	o := New(srv.Client(), NewMemoryStore())
+	o.PollInterval = time.Millisecond
+
+	if _, err := o.Start(context.Background(), ada); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	s, err := o.Wait(ctx, ada.UID)
+	if !er.Is(err, context.DeadlineExceeded) || s.Stage != AwaitingDocuments {
+		t.Errorf("Wait() = %+v, %v; want the deadline while awaiting documents", s, err)
+	}
+}
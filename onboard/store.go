@@ -0,0 +1,43 @@
+package onboard
+
+import (
+	"context"
+	"sync"
+)
+
+// Store persists onboarding state so an onboarding can resume after a
+// restart. Implementations must be safe for concurrent use.
+type Store interface {
+	// Load returns the state saved for uid, or false if there is none.
+	Load(ctx context.Context, uid string) (State, bool, error)
+	Save(ctx context.Context, s State) error
+}
+
+// MemoryStore is a Store held in memory, for tests and single runs.
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]State)}
+}
+
+// Load implements Store.
+func (m *MemoryStore) Load(_ context.Context, uid string) (State, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, found := m.states[uid]
+	return s, found, nil
+}
+
+// Save implements Store.
+func (m *MemoryStore) Save(_ context.Context, s State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.states[s.UID] = s
+	return nil
+}
@@ -0,0 +1,173 @@
+package liquidity
+
+import (
+	er "errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Environment selects the One Liquidity deployment a client talks to.
+type Environment string
+
+const (
+	Sandbox    Environment = "sandbox"
+	Production Environment = "production"
+)
+
+// environmentURLs maps each environment to its base URL.
+var environmentURLs = map[Environment]string{
+	Sandbox:    defaultBaseURL,
+	Production: "https://api.oneliquidity.technology",
+}
+
+// Option configures a Client created with New.
+type Option func(c *config) error
+
+// New creates a One-Liquidity API client. Unlike NewClient it reads nothing
+// from the environment: at least WithAPIKey is required. The options are
+// validated up front and the resulting client is safe for concurrent use.
+func New(opts ...Option) (*Client, error) {
+	cfg := defaultConfig()
+
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Client{cfg: cfg}, nil
+}
+
+func (c config) validate() error {
+	if c.apiKey == "" {
+		return er.New("liquidity: no credentials provided")
+	}
+
+	if c.httpClient == nil {
+		return er.New("liquidity: no HTTP client provided")
+	}
+
+	u, err := url.Parse(c.baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("liquidity: invalid base URL %q", c.baseURL)
+	}
+
+	return nil
+}
+
+// WithAPIKey sets the API key sent with every request.
+func WithAPIKey(apiKey string) Option {
+	return func(c *config) error {
+		if apiKey == "" {
+			return er.New("liquidity: no credentials provided")
+		}
+
+		c.apiKey = bearer(apiKey)
+		return nil
+	}
+}
+
+// WithBaseURL overrides the base URL, e.g. to target a local test server.
+func WithBaseURL(baseURL string) Option {
+	return func(c *config) error {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+		return nil
+	}
+}
+
+// WithEnvironment sets the base URL for env. It defaults to Sandbox.
+func WithEnvironment(env Environment) Option {
+	return func(c *config) error {
+		baseURL, ok := environmentURLs[env]
+		if !ok {
+			return fmt.Errorf("liquidity: unknown environment %q", env)
+		}
+
+		c.baseURL = baseURL
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for API calls.
+func WithHTTPClient(httpClient HTTPClient) Option {
+	return func(c *config) error {
+		if httpClient == nil {
+			return er.New("liquidity: no HTTP client provided")
+		}
+
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithTimeout bounds every call, retries included, by d. It applies whatever
+// HTTP client is in use and on top of any deadline on the call's context.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) error {
+		if d < 0 {
+			return fmt.Errorf("liquidity: negative timeout %s", d)
+		}
+
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header.
+func WithUserAgent(userAgent string) Option {
+	return func(c *config) error {
+		if userAgent == "" {
+			return er.New("liquidity: empty user agent")
+		}
+
+		c.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithLogger sets where debug logs are written. A nil logger discards them.
+func WithLogger(logger Logger) Option {
+	return func(c *config) error {
+		if logger == nil {
+			logger = nopLogger{}
+		}
+
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithDebug enables logging of requests and responses. It is off by default.
+func WithDebug(debug bool) Option {
+	return func(c *config) error {
+		c.debug = debug
+		return nil
+	}
+}
+
+// WithRetryPolicy sets the policy used to retry failed requests.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *config) error {
+		if policy.MaxAttempts < 0 || policy.BaseDelay < 0 || policy.MaxDelay < 0 {
+			return er.New("liquidity: invalid retry policy")
+		}
+
+		c.retry = policy
+		return nil
+	}
+}
+
+// WithIdempotencyStore sets the store used to remember the results of
+// money-moving calls. A nil store disables local replay.
+func WithIdempotencyStore(store IdempotencyStore) Option {
+	return func(c *config) error {
+		c.idempotency = store
+		return nil
+	}
+}
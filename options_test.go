@@ -0,0 +1,139 @@
+package liquidity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	mock := &MockHttpClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			return jsonResponse(200, `{"message":"Ok"}`), nil
+		},
+	}
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantBaseURL string
+		wantErr     bool
+	}{
+		{
+			name:        "defaults to the sandbox",
+			opts:        []Option{WithAPIKey("sk_test")},
+			wantBaseURL: "https://sandbox-api.oneliquidity.technology",
+		},
+		{
+			name:        "selects an environment",
+			opts:        []Option{WithAPIKey("sk_live"), WithEnvironment(Production)},
+			wantBaseURL: "https://api.oneliquidity.technology",
+		},
+		{
+			name:        "overrides the base URL",
+			opts:        []Option{WithAPIKey("sk_test"), WithBaseURL("http://127.0.0.1:8080/"), WithHTTPClient(mock)},
+			wantBaseURL: "http://127.0.0.1:8080",
+		},
+		{name: "requires an API key", opts: nil, wantErr: true},
+		{name: "rejects an empty API key", opts: []Option{WithAPIKey("")}, wantErr: true},
+		{name: "rejects a relative base URL", opts: []Option{WithAPIKey("sk_test"), WithBaseURL("/card")}, wantErr: true},
+		{name: "rejects an unknown environment", opts: []Option{WithAPIKey("sk_test"), WithEnvironment("staging")}, wantErr: true},
+		{name: "rejects a nil HTTP client", opts: []Option{WithAPIKey("sk_test"), WithHTTPClient(nil)}, wantErr: true},
+		{name: "rejects a negative timeout", opts: []Option{WithAPIKey("sk_test"), WithTimeout(-time.Second)}, wantErr: true},
+		{name: "rejects an invalid retry policy", opts: []Option{WithAPIKey("sk_test"), WithRetryPolicy(RetryPolicy{MaxAttempts: -1})}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.config().baseURL; got != tt.wantBaseURL {
+				t.Errorf("baseURL = %q, want %q", got, tt.wantBaseURL)
+			}
+		})
+	}
+}
+
+func TestNew_Headers(t *testing.T) {
+	c, err := New(
+		WithAPIKey("sk_test"),
+		WithUserAgent("payroll/1.2"),
+		WithHTTPClient(&MockHttpClient{
+			DoFunc: func(r *http.Request) (*http.Response, error) {
+				if got := r.Header.Get("Authorization"); got != "Bearer sk_test" {
+					t.Errorf("Authorization = %q, want Bearer sk_test", got)
+				}
+				if got := r.Header.Get("User-Agent"); got != "payroll/1.2" {
+					t.Errorf("User-Agent = %q, want payroll/1.2", got)
+				}
+				return jsonResponse(200, `{"message":"Ok"}`), nil
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err := c.GetIntegratorFloat("USD"); err != nil {
+		t.Errorf("GetIntegratorFloat() error = %v", err)
+	}
+}
+
+func TestNew_Timeout(t *testing.T) {
+	c, err := New(
+		WithAPIKey("sk_test"),
+		WithTimeout(10*time.Millisecond),
+		WithRetryPolicy(NoRetry),
+		WithHTTPClient(&MockHttpClient{
+			DoFunc: func(r *http.Request) (*http.Response, error) {
+				<-r.Context().Done()
+				return nil, r.Context().Err()
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err := c.GetIntegratorFloat("USD"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetIntegratorFloat() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClient_ConcurrentUse(t *testing.T) {
+	c, err := New(
+		WithAPIKey("sk_test"),
+		WithHTTPClient(&MockHttpClient{
+			DoFunc: func(r *http.Request) (*http.Response, error) {
+				return jsonResponse(200, `{"message":"Ok"}`), nil
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetIntegratorFloat("USD"); err != nil {
+				t.Errorf("GetIntegratorFloat() error = %v", err)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			c.SetDebug(i%2 == 0)
+			c.SetLogger(nil)
+		}(i)
+	}
+	wg.Wait()
+}
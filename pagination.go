@@ -0,0 +1,213 @@
+package liquidity
+
+import (
+	"context"
+	er "errors"
+)
+
+// DefaultMaxItems is the cap used by the ListAll helpers when maxItems is not
+// positive.
+const DefaultMaxItems = 10000
+
+// ErrMaxItemsExceeded is returned by the ListAll helpers when there are more
+// items than the cap allows.
+var ErrMaxItemsExceeded = er.New("liquidity: more items than the maximum allowed")
+
+// pager walks the pages of a lek (last evaluated key) paginated endpoint.
+type pager struct {
+	ctx  context.Context
+	lek  string
+	size int
+	pos  int
+	done bool
+	err  error
+}
+
+func newPager(ctx context.Context, lek string) pager {
+	return pager{ctx: ctx, lek: lek, pos: -1}
+}
+
+// advance moves to the next item, calling load for a new page once the
+// current one is used up. load returns the number of items in the page and
+// the cursor of the page after it.
+func (pg *pager) advance(load func(ctx context.Context, lek string) (int, string, error)) bool {
+	pg.pos++
+
+	for pg.pos >= pg.size {
+		if pg.done || pg.err != nil {
+			return false
+		}
+
+		n, lek, err := load(pg.ctx, pg.lek)
+		if err != nil {
+			pg.err = err
+			return false
+		}
+
+		// an empty cursor marks the last page; an empty page or a repeated
+		// cursor would otherwise loop forever
+		if lek == "" || lek == pg.lek || n == 0 {
+			pg.done = true
+		}
+
+		pg.lek, pg.size, pg.pos = lek, n, 0
+	}
+
+	return true
+}
+
+// CardIterator iterates over the cards returned by GetCards across pages.
+type CardIterator struct {
+	pager
+	page []D2
+	load func(ctx context.Context, lek string) (CardsResp, error)
+}
+
+// Next advances to the next card. It returns false when there are no more
+// cards or an error occurred; check Err to tell them apart.
+func (it *CardIterator) Next() bool {
+	return it.advance(func(ctx context.Context, lek string) (int, string, error) {
+		res, err := it.load(ctx, lek)
+		it.page = res.Data
+		return len(res.Data), res.Lek, err
+	})
+}
+
+// Card returns the current card.
+func (it *CardIterator) Card() D2 {
+	return it.page[it.pos]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *CardIterator) Err() error {
+	return it.err
+}
+
+// TransactionIterator iterates over transactions across pages.
+type TransactionIterator struct {
+	pager
+	page []D4
+	load func(ctx context.Context, lek string) (TransactionsResp, error)
+}
+
+// Next advances to the next transaction. It returns false when there are no
+// more transactions or an error occurred; check Err to tell them apart.
+func (it *TransactionIterator) Next() bool {
+	return it.advance(func(ctx context.Context, lek string) (int, string, error) {
+		res, err := it.load(ctx, lek)
+		it.page = res.Data
+		return len(res.Data), res.Lek, err
+	})
+}
+
+// Transaction returns the current transaction.
+func (it *TransactionIterator) Transaction() D4 {
+	return it.page[it.pos]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *TransactionIterator) Err() error {
+	return it.err
+}
+
+// CardsIter returns an iterator over all cards matching p, starting at p.Lek
+func (cl *Client) CardsIter(p Params) *CardIterator {
+	return cl.CardsIterContext(context.Background(), p)
+}
+
+// CardsIterContext is like CardsIter but uses ctx for every page request
+func (cl *Client) CardsIterContext(ctx context.Context, p Params) *CardIterator {
+	return &CardIterator{
+		pager: newPager(ctx, p.Lek),
+		load: func(ctx context.Context, lek string) (CardsResp, error) {
+			p.Lek = lek
+			return cl.GetCardsContext(ctx, p)
+		},
+	}
+}
+
+// TransactionsIter returns an iterator over all transactions of a card, starting at p.Lek
+func (cl *Client) TransactionsIter(cardId string, p Params) *TransactionIterator {
+	return cl.TransactionsIterContext(context.Background(), cardId, p)
+}
+
+// TransactionsIterContext is like TransactionsIter but uses ctx for every page request
+func (cl *Client) TransactionsIterContext(ctx context.Context, cardId string, p Params) *TransactionIterator {
+	return &TransactionIterator{
+		pager: newPager(ctx, p.Lek),
+		load: func(ctx context.Context, lek string) (TransactionsResp, error) {
+			p.Lek = lek
+			return cl.GetTransactionContext(ctx, cardId, p)
+		},
+	}
+}
+
+// FailedTransactionsIter returns an iterator over all failed transactions matching p, starting at p.Lek
+func (cl *Client) FailedTransactionsIter(p Params) *TransactionIterator {
+	return cl.FailedTransactionsIterContext(context.Background(), p)
+}
+
+// FailedTransactionsIterContext is like FailedTransactionsIter but uses ctx for every page request
+func (cl *Client) FailedTransactionsIterContext(ctx context.Context, p Params) *TransactionIterator {
+	return &TransactionIterator{
+		pager: newPager(ctx, p.Lek),
+		load: func(ctx context.Context, lek string) (TransactionsResp, error) {
+			p.Lek = lek
+			return cl.GetFailedTransactionsContext(ctx, p)
+		},
+	}
+}
+
+// ListAllCards follows the cursor until every card matching p has been
+// fetched. It stops with ErrMaxItemsExceeded, returning the first maxItems
+// cards, when there are more than maxItems.
+func (cl *Client) ListAllCards(ctx context.Context, p Params, maxItems int) ([]D2, error) {
+	maxItems = maxItemsOrDefault(maxItems)
+
+	var cards []D2
+	it := cl.CardsIterContext(ctx, p)
+	for it.Next() {
+		if len(cards) == maxItems {
+			return cards, ErrMaxItemsExceeded
+		}
+		cards = append(cards, it.Card())
+	}
+
+	return cards, it.Err()
+}
+
+// ListAllTransactions follows the cursor until every transaction of a card
+// has been fetched. It stops with ErrMaxItemsExceeded, returning the first
+// maxItems transactions, when there are more than maxItems.
+func (cl *Client) ListAllTransactions(ctx context.Context, cardId string, p Params, maxItems int) ([]D4, error) {
+	return listAllTransactions(cl.TransactionsIterContext(ctx, cardId, p), maxItems)
+}
+
+// ListAllFailedTransactions follows the cursor until every failed transaction
+// matching p has been fetched. It stops with ErrMaxItemsExceeded, returning
+// the first maxItems transactions, when there are more than maxItems.
+func (cl *Client) ListAllFailedTransactions(ctx context.Context, p Params, maxItems int) ([]D4, error) {
+	return listAllTransactions(cl.FailedTransactionsIterContext(ctx, p), maxItems)
+}
+
+func listAllTransactions(it *TransactionIterator, maxItems int) ([]D4, error) {
+	maxItems = maxItemsOrDefault(maxItems)
+
+	var txns []D4
+	for it.Next() {
+		if len(txns) == maxItems {
+			return txns, ErrMaxItemsExceeded
+		}
+		txns = append(txns, it.Transaction())
+	}
+
+	return txns, it.Err()
+}
+
+func maxItemsOrDefault(maxItems int) int {
+	if maxItems <= 0 {
+		return DefaultMaxItems
+	}
+
+	return maxItems
+}
@@ -0,0 +1,112 @@
+package liquidity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// pagedMock serves pages of ids keyed by the lek query parameter.
+func pagedMock(t *testing.T, path string, pages map[string][]string, next map[string]string, leks *[]string) *MockHttpClient {
+	return &MockHttpClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path != path {
+				t.Errorf("Expected to request '%s', got: %s", path, r.URL.Path)
+			}
+			lek := r.URL.Query().Get("lek")
+			*leks = append(*leks, lek)
+
+			data := "["
+			for i, id := range pages[lek] {
+				if i > 0 {
+					data += ","
+				}
+				data += fmt.Sprintf(`{"cardId":%q,"transactionId":%q}`, id, id)
+			}
+			data += "]"
+
+			return jsonResponse(200, fmt.Sprintf(`{"message":"Ok","data":%s,"lek":%q}`, data, next[lek])), nil
+		},
+	}
+}
+
+func TestClient_CardsIter(t *testing.T) {
+	var leks []string
+	c := newTestClient(pagedMock(t, "/cards/v1",
+		map[string][]string{"": {"c1", "c2"}, "k1": {"c3"}, "k2": {}},
+		map[string]string{"": "k1", "k1": "k2", "k2": ""},
+		&leks,
+	))
+
+	var got []string
+	it := c.CardsIter(Params{Id: "e08078bd-9384-5b7e-93c5-76be956380fe", Limit: 2})
+	for it.Next() {
+		got = append(got, it.Card().CardId)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+
+	if want := []string{"c1", "c2", "c3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cards = %v, want %v", got, want)
+	}
+	if want := []string{"", "k1", "k2"}; !reflect.DeepEqual(leks, want) {
+		t.Errorf("requested leks = %q, want %q", leks, want)
+	}
+}
+
+func TestClient_TransactionsIterStopsOnRepeatedCursor(t *testing.T) {
+	var leks []string
+	c := newTestClient(pagedMock(t, "/card/v1/transactions",
+		map[string][]string{"": {"t1"}, "k1": {"t2"}},
+		map[string]string{"": "k1", "k1": "k1"},
+		&leks,
+	))
+
+	got, err := c.ListAllTransactions(context.Background(), "c954e4c9-8ca8-4d1d-8ebf-bb374e9f1b1d", Params{}, 0)
+	if err != nil {
+		t.Fatalf("ListAllTransactions() error = %v", err)
+	}
+	if len(got) != 2 || got[0].TransactionId != "t1" || got[1].TransactionId != "t2" {
+		t.Errorf("ListAllTransactions() got = %v", got)
+	}
+	if len(leks) != 2 {
+		t.Errorf("requested leks = %q, want 2 pages", leks)
+	}
+}
+
+func TestClient_ListAllFailedTransactionsCap(t *testing.T) {
+	var leks []string
+	c := newTestClient(pagedMock(t, "/card/v1/transactions/failed",
+		map[string][]string{"": {"t1", "t2"}, "k1": {"t3", "t4"}},
+		map[string]string{"": "k1", "k1": ""},
+		&leks,
+	))
+
+	got, err := c.ListAllFailedTransactions(context.Background(), Params{}, 3)
+	if !errors.Is(err, ErrMaxItemsExceeded) {
+		t.Fatalf("ListAllFailedTransactions() error = %v, want %v", err, ErrMaxItemsExceeded)
+	}
+	if len(got) != 3 {
+		t.Errorf("ListAllFailedTransactions() returned %d items, want 3", len(got))
+	}
+}
+
+func TestClient_CardsIterError(t *testing.T) {
+	c := newTestClient(&MockHttpClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			return jsonResponse(401, `{"message":"Unauthorized"}`), nil
+		},
+	})
+
+	it := c.CardsIter(Params{})
+	if it.Next() {
+		t.Fatalf("Next() = true, want false")
+	}
+	if !IsUnauthorized(it.Err()) {
+		t.Errorf("Err() = %v, want an unauthorized error", it.Err())
+	}
+}
@@ -0,0 +1,299 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// ChainIssueKind classifies a break in a card's balance chain.
+type ChainIssueKind string
+
+const (
+	// ChainGap is a transaction whose balance before differs from the
+	// balance after the previous one, or a history that does not start at
+	// zero.
+	ChainGap ChainIssueKind = "gap"
+	// ChainReordered is a transaction whose balances place it after one
+	// stamped later, so the two happened in a different order than their
+	// timestamps say.
+	ChainReordered ChainIssueKind = "reordered"
+	// ChainArithmetic is a transaction whose balance after is not its
+	// balance before plus or minus its amount.
+	ChainArithmetic ChainIssueKind = "arithmetic"
+	// ChainBalanceMismatch is a card whose current balance differs from the
+	// balance after its last transaction.
+	ChainBalanceMismatch ChainIssueKind = "balance_mismatch"
+)
+
+// ChainIssue is one break in a balance chain. Expected and Actual are the
+// balances that should have been equal.
+type ChainIssue struct {
+	Kind          ChainIssueKind
+	TransactionID string
+	// Index is the position of the transaction in time order, or -1 for
+	// ChainBalanceMismatch.
+	Index    int
+	Expected liquidity.Money
+	Actual   liquidity.Money
+	Detail   string
+}
+
+// ChainReport is the result of checking a card's balance chain.
+type ChainReport struct {
+	CardID       string
+	Transactions int
+	// Opening is the balance before the first transaction and Closing the
+	// balance after the last, in chain order.
+	Opening liquidity.Money
+	Closing liquidity.Money
+	// Current is the card's balance, when known.
+	Current *liquidity.Money
+	Issues  []ChainIssue
+}
+
+// OK reports whether the chain is unbroken.
+func (r *ChainReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// CardSource reads a card and its transactions. *liquidity.Client satisfies
+// it.
+type CardSource interface {
+	GetCardContext(ctx context.Context, card string, trackingNumber string) (liquidity.CardResp, error)
+	ListAllTransactions(ctx context.Context, cardId string, p liquidity.Params, maxItems int) ([]liquidity.D4, error)
+}
+
+// VerifyChain walks the full transaction history of a card, across every
+// page, and checks that each transaction starts where the previous one left
+// off, that its own arithmetic holds, and that the last one ends at the
+// card's current balance. maxItems caps the history fetched; zero means
+// liquidity.DefaultMaxItems.
+func VerifyChain(ctx context.Context, src CardSource, cardID string, maxItems int) (*ChainReport, error) {
+	txns, err := src.ListAllTransactions(ctx, cardID, liquidity.Params{}, maxItems)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: listing transactions of card %s: %w", cardID, err)
+	}
+
+	// Read the card after its history, so transactions made in between
+	// show up as a balance mismatch rather than being missed.
+	card, err := src.GetCardContext(ctx, cardID, "")
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: reading card %s: %w", cardID, err)
+	}
+
+	return CheckChain(cardID, txns, &card.Data.Balance), nil
+}
+
+// CheckChain checks the balance chain of a card's complete history. current
+// is the card's balance, or nil to skip that check.
+func CheckChain(cardID string, txns []liquidity.D4, current *liquidity.Money) *ChainReport {
+	ptrs := make([]*liquidity.D4, len(txns))
+	for i := range txns {
+		ptrs[i] = &txns[i]
+	}
+
+	// Balances are compared by their formatted amounts, so the opening zero
+	// needs the card's currency.
+	zero := liquidity.Money{}
+	if len(txns) > 0 {
+		zero.Currency = txns[0].TransactionBalanceBefore.Currency
+	}
+	chain, issues := walkChain(byTime(ptrs), &zero)
+
+	report := &ChainReport{
+		CardID:       cardID,
+		Transactions: len(chain),
+		Current:      current,
+		Issues:       issues,
+	}
+
+	if len(chain) > 0 {
+		report.Opening = chain[0].TransactionBalanceBefore
+		report.Closing = chain[len(chain)-1].CardBalanceAfter
+	}
+
+	if current != nil && current.Cmp(report.Closing) != 0 {
+		report.Issues = append(report.Issues, ChainIssue{
+			Kind:     ChainBalanceMismatch,
+			Index:    -1,
+			Expected: report.Closing,
+			Actual:   *current,
+			Detail:   fmt.Sprintf("card balance is %s, but its last transaction left %s", *current, report.Closing),
+		})
+	}
+
+	return report
+}
+
+// checkChain reports breaks in the balance chain of part of a card's
+// history, for the reconciler.
+func checkChain(txns []*liquidity.D4) []Discrepancy {
+	ordered := byTime(txns)
+	_, issues := walkChain(ordered, nil)
+
+	var breaks []Discrepancy
+	for _, issue := range issues {
+		breaks = append(breaks, Discrepancy{
+			Kind:          ChainBreak,
+			TransactionID: issue.TransactionID,
+			Provider:      ordered[issue.Index],
+			Detail:        issue.Detail,
+		})
+	}
+
+	return breaks
+}
+
+// byTime returns txns sorted by creation time, keeping the listed order for
+// ties and unparseable times.
+func byTime(txns []*liquidity.D4) []*liquidity.D4 {
+	ordered := append([]*liquidity.D4(nil), txns...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, _ := parseTime(ordered[i].CreatedAt)
+		b, _ := parseTime(ordered[j].CreatedAt)
+		return a.Before(b)
+	})
+
+	return ordered
+}
+
+// walkChain checks transactions given in time order. opening, when not nil,
+// is the balance the first transaction must start from. If the balances link
+// every transaction into a chain in a different order than the timestamps,
+// the transactions that moved are reported as reordered and the chain order
+// is returned; otherwise the time order is checked for gaps.
+func walkChain(ordered []*liquidity.D4, opening *liquidity.Money) ([]*liquidity.D4, []ChainIssue) {
+	var issues []ChainIssue
+
+	for i, t := range ordered {
+		if want, ok := expectedAfter(t); ok && want.Cmp(t.CardBalanceAfter) != 0 {
+			issues = append(issues, ChainIssue{
+				Kind:          ChainArithmetic,
+				TransactionID: t.TransactionId,
+				Index:         i,
+				Expected:      want,
+				Actual:        t.CardBalanceAfter,
+				Detail:        fmt.Sprintf("%s of %s from %s should leave %s, not %s", t.Type, t.Amount, t.TransactionBalanceBefore, want, t.CardBalanceAfter),
+			})
+		}
+	}
+
+	if chain, ok := linkChain(ordered, opening); ok {
+		pos := make(map[*liquidity.D4]int, len(ordered))
+		for i, t := range ordered {
+			pos[t] = i
+		}
+
+		for i, t := range chain {
+			if pos[t] >= i {
+				continue
+			}
+			issues = append(issues, ChainIssue{
+				Kind:          ChainReordered,
+				TransactionID: t.TransactionId,
+				Index:         pos[t],
+				Expected:      chain[i-1].CardBalanceAfter,
+				Actual:        t.TransactionBalanceBefore,
+				Detail:        fmt.Sprintf("stamped %s, but its balance before follows %s, stamped %s", t.CreatedAt, chain[i-1].TransactionId, chain[i-1].CreatedAt),
+			})
+		}
+
+		return chain, issues
+	}
+
+	for i, t := range ordered {
+		var want liquidity.Money
+		var from string
+		switch {
+		case i > 0:
+			want, from = ordered[i-1].CardBalanceAfter, ordered[i-1].TransactionId+" left"
+		case opening != nil:
+			want, from = *opening, "the history starts at"
+		default:
+			continue
+		}
+
+		if t.TransactionBalanceBefore.Cmp(want) != 0 {
+			issues = append(issues, ChainIssue{
+				Kind:          ChainGap,
+				TransactionID: t.TransactionId,
+				Index:         i,
+				Expected:      want,
+				Actual:        t.TransactionBalanceBefore,
+				Detail:        fmt.Sprintf("balance before is %s, but %s %s", t.TransactionBalanceBefore, from, want),
+			})
+		}
+	}
+
+	return ordered, issues
+}
+
+// linkChain orders transactions by following balances from opening, or from
+// the earliest transaction no other one leads into. Among transactions
+// starting from the same balance the earliest stamped comes first. It fails
+// if the balances do not link every transaction.
+func linkChain(ordered []*liquidity.D4, opening *liquidity.Money) ([]*liquidity.D4, bool) {
+	if len(ordered) == 0 {
+		return nil, true
+	}
+
+	// Transactions by balance before, each queue in time order.
+	from := make(map[string][]*liquidity.D4)
+	leadsTo := make(map[string]int)
+	for _, t := range ordered {
+		before := t.TransactionBalanceBefore.Amount()
+		from[before] = append(from[before], t)
+		leadsTo[t.CardBalanceAfter.Amount()]++
+	}
+
+	var cur string
+	switch {
+	case opening != nil:
+		cur = opening.Amount()
+	default:
+		cur = ordered[0].TransactionBalanceBefore.Amount()
+		for _, t := range ordered {
+			if before := t.TransactionBalanceBefore.Amount(); leadsTo[before] == 0 {
+				cur = before
+				break
+			}
+		}
+	}
+
+	chain := make([]*liquidity.D4, 0, len(ordered))
+	for len(chain) < len(ordered) {
+		queue := from[cur]
+		if len(queue) == 0 {
+			return nil, false
+		}
+
+		t := queue[0]
+		from[cur] = queue[1:]
+		chain = append(chain, t)
+		cur = t.CardBalanceAfter.Amount()
+	}
+
+	return chain, true
+}
+
+// expectedAfter returns the balance a transaction should leave, if its type
+// is known.
+func expectedAfter(t *liquidity.D4) (liquidity.Money, bool) {
+	var after liquidity.Money
+	var err error
+
+	switch strings.ToLower(t.Type) {
+	case "credit":
+		after, err = t.TransactionBalanceBefore.Add(t.Amount)
+	case "debit":
+		after, err = t.TransactionBalanceBefore.Sub(t.Amount)
+	default:
+		return liquidity.Money{}, false
+	}
+
+	return after, err == nil
+}
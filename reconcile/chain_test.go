@@ -0,0 +1,143 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+	"github.com/bushaHQ/one-liquidity-go/liquiditytest"
+)
+
+func TestCheckChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		txns    []liquidity.D4
+		current string
+		want    []ChainIssueKind
+	}{
+		{
+			name: "unbroken",
+			txns: []liquidity.D4{
+				txn("t1", "credit", "10", "0", "10", 0),
+				txn("t2", "debit", "2.50", "10", "7.50", time.Minute),
+			},
+			current: "7.50",
+		},
+		{
+			name:    "empty",
+			current: "0",
+		},
+		{
+			name: "listed out of time order",
+			txns: []liquidity.D4{
+				txn("t2", "debit", "2.50", "10", "7.50", time.Minute),
+				txn("t1", "credit", "10", "0", "10", 0),
+			},
+			current: "7.50",
+		},
+		{
+			name: "gap",
+			txns: []liquidity.D4{
+				txn("t1", "credit", "10", "0", "10", 0),
+				txn("t2", "debit", "2", "9", "7", time.Minute),
+			},
+			current: "7",
+			want:    []ChainIssueKind{ChainGap},
+		},
+		{
+			name:    "history does not start at zero",
+			txns:    []liquidity.D4{txn("t1", "credit", "10", "5", "15", 0)},
+			current: "15",
+			want:    []ChainIssueKind{ChainGap},
+		},
+		{
+			name: "arithmetic",
+			txns: []liquidity.D4{
+				txn("t1", "credit", "10", "0", "11", 0),
+			},
+			current: "11",
+			want:    []ChainIssueKind{ChainArithmetic},
+		},
+		{
+			name: "timestamps swapped",
+			txns: []liquidity.D4{
+				txn("t1", "credit", "10", "0", "10", time.Minute),
+				txn("t2", "credit", "5", "10", "15", 0),
+			},
+			current: "15",
+			want:    []ChainIssueKind{ChainReordered},
+		},
+		{
+			name:    "current balance differs",
+			txns:    []liquidity.D4{txn("t1", "credit", "10", "0", "10", 0)},
+			current: "12",
+			want:    []ChainIssueKind{ChainBalanceMismatch},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := usd(tt.current)
+			report := CheckChain("card-1", tt.txns, &current)
+
+			if len(report.Issues) != len(tt.want) {
+				t.Fatalf("Issues = %+v, want %v", report.Issues, tt.want)
+			}
+			for i, kind := range tt.want {
+				if report.Issues[i].Kind != kind {
+					t.Errorf("Issues[%d] = %+v, want %s", i, report.Issues[i], kind)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckChain_Discrepancies(t *testing.T) {
+	txns := []liquidity.D4{
+		txn("t2", "debit", "2", "9", "7", time.Minute),
+		txn("t1", "credit", "10", "0", "10", 0),
+	}
+
+	breaks := checkChain([]*liquidity.D4{&txns[0], &txns[1]})
+	if len(breaks) != 1 {
+		t.Fatalf("checkChain() = %+v, want one break", breaks)
+	}
+	if d := breaks[0]; d.Kind != ChainBreak || d.TransactionID != "t2" || d.Provider != &txns[0] {
+		t.Errorf("checkChain() = %+v, want a break at t2 carrying the provider transaction", d)
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	srv := liquiditytest.NewServer()
+	defer srv.Close()
+
+	cl := srv.Client()
+	srv.SetFloat(usd("1000"))
+	userID := srv.AddUser(liquidity.CreateUserData{FirstName: "Ada", UID: "u-1"})
+
+	card, err := cl.CreateCard(liquidity.CreateCardData{UserId: userID, Expiry: "2099-01-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cardID := card.Data.CardId
+
+	// More transactions than fit on a page.
+	for i := 0; i < 15; i++ {
+		if _, err := cl.TopUp(cardID, usd("3")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cl.Debit(cardID, usd("1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := VerifyChain(context.Background(), cl, cardID, 0)
+	if err != nil {
+		t.Fatalf("VerifyChain() error = %v", err)
+	}
+
+	if !report.OK() || report.Transactions != 30 || !report.Closing.Equal(usd("30")) {
+		t.Errorf("VerifyChain() = %+v, want 30 transactions closing at 30.00 USD and no issues", report)
+	}
+}
@@ -0,0 +1,357 @@
+// Package reconcile compares the card transactions One Liquidity reports
+// against an internal ledger and lists every record that does not line up.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// DefaultTimeTolerance is how far apart the provider's and the ledger's
+// timestamps may be for records without a shared transaction ID to match.
+const DefaultTimeTolerance = 5 * time.Minute
+
+// Entry is a ledger record of a card transaction.
+type Entry struct {
+	// ID identifies the entry in the ledger.
+	ID string
+	// TransactionID is the One Liquidity transaction ID, if the ledger
+	// stored it. Entries without one are matched on amount and time.
+	TransactionID string
+	CardID        string
+	// Type is "credit" or "debit", as in liquidity.D4.
+	Type   string
+	Amount liquidity.Money
+	Time   time.Time
+}
+
+// LedgerSource returns the ledger entries of the given cards with times in
+// [from, to).
+type LedgerSource interface {
+	Entries(ctx context.Context, cardIDs []string, from, to time.Time) ([]Entry, error)
+}
+
+// TransactionSource lists transactions from One Liquidity. *liquidity.Client
+// satisfies it.
+type TransactionSource interface {
+	ListAllTransactions(ctx context.Context, cardId string, p liquidity.Params, maxItems int) ([]liquidity.D4, error)
+	ListAllFailedTransactions(ctx context.Context, p liquidity.Params, maxItems int) ([]liquidity.D4, error)
+}
+
+// Kind classifies a discrepancy.
+type Kind string
+
+const (
+	// MissingInLedger is a provider transaction with no ledger entry.
+	MissingInLedger Kind = "missing_in_ledger"
+	// MissingAtProvider is a ledger entry with no provider transaction.
+	MissingAtProvider Kind = "missing_at_provider"
+	// Duplicate is a transaction ID that appears more than once on the
+	// same side.
+	Duplicate Kind = "duplicate"
+	// AmountMismatch is a matched pair whose amount, currency or direction
+	// differ.
+	AmountMismatch Kind = "amount_mismatch"
+	// FailedInLedger is a ledger entry for a transaction the provider
+	// declined.
+	FailedInLedger Kind = "failed_in_ledger"
+	// ChainBreak is a transaction whose balance before does not equal the
+	// balance after the previous transaction of the card.
+	ChainBreak Kind = "chain_break"
+)
+
+// Discrepancy is one record that does not reconcile. Provider and Ledger
+// hold whichever sides exist.
+type Discrepancy struct {
+	Kind          Kind
+	CardID        string
+	TransactionID string
+	Provider      *liquidity.D4
+	Ledger        *Entry
+	Detail        string
+}
+
+func (d Discrepancy) String() string {
+	s := string(d.Kind) + " card=" + d.CardID
+	if d.TransactionID != "" {
+		s += " transaction=" + d.TransactionID
+	}
+	if d.Ledger != nil && d.Ledger.ID != "" {
+		s += " entry=" + d.Ledger.ID
+	}
+	if d.Detail != "" {
+		s += ": " + d.Detail
+	}
+
+	return s
+}
+
+// Report is the outcome of a reconciliation run.
+type Report struct {
+	From, To time.Time
+	CardIDs  []string
+	// Matched counts the pairs that agree.
+	Matched       int
+	Discrepancies []Discrepancy
+}
+
+// OK reports whether everything reconciled.
+func (r *Report) OK() bool {
+	return len(r.Discrepancies) == 0
+}
+
+// ByKind returns the discrepancies of one kind.
+func (r *Report) ByKind(kind Kind) []Discrepancy {
+	var ds []Discrepancy
+	for _, d := range r.Discrepancies {
+		if d.Kind == kind {
+			ds = append(ds, d)
+		}
+	}
+
+	return ds
+}
+
+// Reconciler matches provider transactions against ledger entries.
+type Reconciler struct {
+	Provider TransactionSource
+	Ledger   LedgerSource
+
+	// TimeTolerance bounds the time difference when matching on amount.
+	// Zero means DefaultTimeTolerance.
+	TimeTolerance time.Duration
+	// MaxItems caps the transactions fetched per card. Zero means
+	// liquidity.DefaultMaxItems.
+	MaxItems int
+}
+
+// New returns a Reconciler for provider and ledger.
+func New(provider TransactionSource, ledger LedgerSource) *Reconciler {
+	return &Reconciler{Provider: provider, Ledger: ledger}
+}
+
+func (rc *Reconciler) tolerance() time.Duration {
+	if rc.TimeTolerance > 0 {
+		return rc.TimeTolerance
+	}
+
+	return DefaultTimeTolerance
+}
+
+// Run reconciles the given cards over [from, to).
+//
+// Provider transactions are fetched for a window widened by the time
+// tolerance, so a ledger entry near an edge still finds its match, but only
+// those inside [from, to) are reported as missing from the ledger.
+func (rc *Reconciler) Run(ctx context.Context, cardIDs []string, from, to time.Time) (*Report, error) {
+	report := &Report{From: from, To: to, CardIDs: cardIDs}
+
+	entries, err := rc.Ledger.Entries(ctx, cardIDs, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: reading ledger: %w", err)
+	}
+
+	byCard := make(map[string][]Entry)
+	for _, e := range entries {
+		byCard[e.CardID] = append(byCard[e.CardID], e)
+	}
+
+	tol := rc.tolerance()
+	p := liquidity.Params{
+		StartDate: from.Add(-tol).UTC().Format("2006-01-02"),
+		EndDate:   to.Add(tol).UTC().Format("2006-01-02"),
+	}
+
+	for _, cardID := range cardIDs {
+		txns, err := rc.Provider.ListAllTransactions(ctx, cardID, p, rc.MaxItems)
+		if err != nil {
+			return nil, fmt.Errorf("reconcile: listing transactions of card %s: %w", cardID, err)
+		}
+
+		fp := p
+		fp.Id = cardID
+		failed, err := rc.Provider.ListAllFailedTransactions(ctx, fp, rc.MaxItems)
+		if err != nil {
+			return nil, fmt.Errorf("reconcile: listing failed transactions of card %s: %w", cardID, err)
+		}
+
+		rc.reconcileCard(report, cardID, txns, failed, byCard[cardID])
+	}
+
+	sort.SliceStable(report.Discrepancies, func(i, j int) bool {
+		return report.Discrepancies[i].CardID < report.Discrepancies[j].CardID
+	})
+
+	return report, nil
+}
+
+func (rc *Reconciler) reconcileCard(report *Report, cardID string, txns, failed []liquidity.D4, entries []Entry) {
+	add := func(d Discrepancy) {
+		d.CardID = cardID
+		report.Discrepancies = append(report.Discrepancies, d)
+	}
+
+	inWindow := func(t time.Time) bool {
+		return !t.Before(report.From) && t.Before(report.To)
+	}
+
+	// Provider side: drop repeats of the same transaction ID.
+	var provider []*liquidity.D4
+	seen := make(map[string]bool)
+	for i := range txns {
+		t := &txns[i]
+		if seen[t.TransactionId] {
+			add(Discrepancy{Kind: Duplicate, TransactionID: t.TransactionId, Provider: t, Detail: "listed more than once by the provider"})
+			continue
+		}
+		seen[t.TransactionId] = true
+		provider = append(provider, t)
+	}
+
+	for _, b := range checkChain(provider) {
+		add(b)
+	}
+
+	declined := make(map[string]*liquidity.D4)
+	for i := range failed {
+		declined[failed[i].TransactionId] = &failed[i]
+	}
+
+	byID := make(map[string]*liquidity.D4)
+	for _, t := range provider {
+		byID[t.TransactionId] = t
+	}
+
+	matched := make(map[*liquidity.D4]bool)
+	var unmatched []*Entry
+
+	// Ledger side: match on transaction ID first.
+	ledgerSeen := make(map[string]bool)
+	for i := range entries {
+		e := &entries[i]
+
+		if e.TransactionID == "" {
+			unmatched = append(unmatched, e)
+			continue
+		}
+
+		if ledgerSeen[e.TransactionID] {
+			add(Discrepancy{Kind: Duplicate, TransactionID: e.TransactionID, Ledger: e, Detail: "recorded more than once in the ledger"})
+			continue
+		}
+		ledgerSeen[e.TransactionID] = true
+
+		t, found := byID[e.TransactionID]
+		if !found {
+			if f, ok := declined[e.TransactionID]; ok {
+				add(Discrepancy{Kind: FailedInLedger, TransactionID: e.TransactionID, Provider: f, Ledger: e, Detail: f.ErrorDescription})
+				continue
+			}
+			add(Discrepancy{Kind: MissingAtProvider, TransactionID: e.TransactionID, Ledger: e})
+			continue
+		}
+
+		matched[t] = true
+		if detail := compare(t, e); detail != "" {
+			add(Discrepancy{Kind: AmountMismatch, TransactionID: t.TransactionId, Provider: t, Ledger: e, Detail: detail})
+			continue
+		}
+		report.Matched++
+	}
+
+	// Then match the remaining entries on direction, amount and time,
+	// taking the closest provider transaction in time.
+	tol := rc.tolerance()
+	for _, e := range unmatched {
+		var best *liquidity.D4
+		var bestDiff time.Duration
+
+		for _, t := range provider {
+			if matched[t] || compare(t, e) != "" {
+				continue
+			}
+
+			at, ok := parseTime(t.CreatedAt)
+			if !ok {
+				continue
+			}
+
+			diff := at.Sub(e.Time)
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff <= tol && (best == nil || diff < bestDiff) {
+				best, bestDiff = t, diff
+			}
+		}
+
+		if best == nil {
+			add(Discrepancy{Kind: MissingAtProvider, Ledger: e})
+			continue
+		}
+
+		matched[best] = true
+		report.Matched++
+	}
+
+	for _, t := range provider {
+		if matched[t] {
+			continue
+		}
+
+		if at, ok := parseTime(t.CreatedAt); ok && !inWindow(at) {
+			continue
+		}
+
+		add(Discrepancy{Kind: MissingInLedger, TransactionID: t.TransactionId, Provider: t})
+	}
+}
+
+// compare describes how a provider transaction and a ledger entry disagree,
+// or returns "" if they agree.
+func compare(t *liquidity.D4, e *Entry) string {
+	var diffs []string
+
+	if !strings.EqualFold(t.Type, e.Type) {
+		diffs = append(diffs, fmt.Sprintf("type %s != %s", t.Type, e.Type))
+	}
+
+	if t.Amount.Cmp(e.Amount) != 0 {
+		diffs = append(diffs, fmt.Sprintf("amount %s != %s", t.Amount, e.Amount))
+	} else if e.Amount.Currency != "" && t.Amount.Currency != "" && !strings.EqualFold(t.Amount.Currency, e.Amount.Currency) {
+		diffs = append(diffs, fmt.Sprintf("currency %s != %s", t.Amount.Currency, e.Amount.Currency))
+	}
+
+	return strings.Join(diffs, ", ")
+}
+
+func parseTime(s string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
+
+// Entries is a LedgerSource over entries already in memory, e.g. loaded from
+// an export.
+type Entries []Entry
+
+// Entries returns the entries of the given cards with times in [from, to).
+func (es Entries) Entries(ctx context.Context, cardIDs []string, from, to time.Time) ([]Entry, error) {
+	cards := make(map[string]bool, len(cardIDs))
+	for _, id := range cardIDs {
+		cards[id] = true
+	}
+
+	var out []Entry
+	for _, e := range es {
+		if cards[e.CardID] && !e.Time.Before(from) && e.Time.Before(to) {
+			out = append(out, e)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,176 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+var _ TransactionSource = (*liquidity.Client)(nil)
+
+type fakeProvider struct {
+	txns   map[string][]liquidity.D4
+	failed []liquidity.D4
+}
+
+func (f fakeProvider) ListAllTransactions(ctx context.Context, cardId string, p liquidity.Params, maxItems int) ([]liquidity.D4, error) {
+	return f.txns[cardId], nil
+}
+
+func (f fakeProvider) ListAllFailedTransactions(ctx context.Context, p liquidity.Params, maxItems int) ([]liquidity.D4, error) {
+	var out []liquidity.D4
+	for _, t := range f.failed {
+		if t.CardId == p.Id {
+			out = append(out, t)
+		}
+	}
+
+	return out, nil
+}
+
+var base = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func usd(s string) liquidity.Money {
+	return liquidity.MustParseMoney(s, "USD")
+}
+
+func txn(id, kind, amount, before, after string, at time.Duration) liquidity.D4 {
+	return liquidity.D4{
+		TransactionId:            id,
+		CardId:                   "card-1",
+		Type:                     kind,
+		Amount:                   usd(amount),
+		TransactionBalanceBefore: usd(before),
+		CardBalanceAfter:         usd(after),
+		Currency:                 "USD",
+		CreatedAt:                base.Add(at).Format(time.RFC3339),
+	}
+}
+
+func entry(id, txnID, kind, amount string, at time.Duration) Entry {
+	return Entry{ID: id, TransactionID: txnID, CardID: "card-1", Type: kind, Amount: usd(amount), Time: base.Add(at)}
+}
+
+func TestReconciler_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		txns    []liquidity.D4
+		failed  []liquidity.D4
+		ledger  Entries
+		matched int
+		want    []Kind
+	}{
+		{
+			name: "all match",
+			txns: []liquidity.D4{
+				txn("t1", "credit", "10", "0", "10", 0),
+				txn("t2", "debit", "4", "10", "6", time.Hour),
+			},
+			ledger: Entries{
+				entry("e1", "t1", "credit", "10", 0),
+				entry("e2", "", "debit", "4.00", time.Hour+2*time.Minute),
+			},
+			matched: 2,
+		},
+		{
+			name:   "missing on each side",
+			txns:   []liquidity.D4{txn("t1", "credit", "10", "0", "10", 0)},
+			ledger: Entries{entry("e1", "t9", "credit", "10", 0)},
+			want:   []Kind{MissingAtProvider, MissingInLedger},
+		},
+		{
+			name:   "amount mismatch",
+			txns:   []liquidity.D4{txn("t1", "credit", "10", "0", "10", 0)},
+			ledger: Entries{entry("e1", "t1", "credit", "10.01", 0)},
+			want:   []Kind{AmountMismatch},
+		},
+		{
+			name:   "outside time tolerance",
+			txns:   []liquidity.D4{txn("t1", "credit", "10", "0", "10", 0)},
+			ledger: Entries{entry("e1", "", "credit", "10", 10*time.Minute)},
+			want:   []Kind{MissingAtProvider, MissingInLedger},
+		},
+		{
+			name: "duplicates",
+			txns: []liquidity.D4{
+				txn("t1", "credit", "10", "0", "10", 0),
+				txn("t1", "credit", "10", "0", "10", 0),
+			},
+			ledger: Entries{
+				entry("e1", "t1", "credit", "10", 0),
+				entry("e2", "t1", "credit", "10", 0),
+			},
+			matched: 1,
+			want:    []Kind{Duplicate, Duplicate},
+		},
+		{
+			name:   "declined transaction booked",
+			failed: []liquidity.D4{txn("f1", "credit", "10", "0", "0", 0)},
+			ledger: Entries{entry("e1", "f1", "credit", "10", 0)},
+			want:   []Kind{FailedInLedger},
+		},
+		{
+			name: "chain break",
+			txns: []liquidity.D4{
+				txn("t1", "credit", "10", "0", "10", 0),
+				txn("t2", "credit", "5", "12", "17", time.Hour),
+			},
+			ledger: Entries{
+				entry("e1", "t1", "credit", "10", 0),
+				entry("e2", "t2", "credit", "5", time.Hour),
+			},
+			matched: 2,
+			want:    []Kind{ChainBreak},
+		},
+		{
+			name: "provider transaction just outside the window",
+			txns: []liquidity.D4{
+				txn("t1", "credit", "10", "0", "10", -2*time.Minute),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failed := tt.failed
+			for i := range failed {
+				failed[i].CardId = "card-1"
+			}
+
+			rc := New(fakeProvider{txns: map[string][]liquidity.D4{"card-1": tt.txns}, failed: failed}, tt.ledger)
+
+			report, err := rc.Run(context.Background(), []string{"card-1"}, base, base.Add(24*time.Hour))
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if report.Matched != tt.matched {
+				t.Errorf("Matched = %d, want %d", report.Matched, tt.matched)
+			}
+
+			got := make(map[Kind]int)
+			for _, d := range report.Discrepancies {
+				got[d.Kind]++
+			}
+			want := make(map[Kind]int)
+			for _, k := range tt.want {
+				want[k]++
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("Discrepancies = %v, want kinds %v", report.Discrepancies, tt.want)
+			}
+			for k, n := range want {
+				if got[k] != n {
+					t.Errorf("Discrepancies = %v, want kinds %v", report.Discrepancies, tt.want)
+				}
+			}
+
+			if report.OK() != (len(tt.want) == 0) {
+				t.Errorf("OK() = %v with %d discrepancies", report.OK(), len(report.Discrepancies))
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package liquidity
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveFields are the lower-cased JSON and query keys whose values never
+// reach the logs: card data, credentials, pre-signed URLs and user PII.
+var sensitiveFields = map[string]bool{
+	"cardnumber":      true,
+	"cvv2":            true,
+	"cvv":             true,
+	"expiry":          true,
+	"valid":           true,
+	"pin":             true,
+	"authorization":   true,
+	"apikey":          true,
+	"firstname":       true,
+	"lastname":        true,
+	"cardname":        true,
+	"email":           true,
+	"contactnumber":   true,
+	"address":         true,
+	"businessaddress": true,
+	"city":            true,
+	"postalcode":      true,
+	"uid":             true,
+	"selfieuploadurl": true,
+	"iduploadurl":     true,
+	"accountnumber":   true,
+}
+
+// redactJSON masks sensitive fields in a JSON document. Bodies that are not
+// JSON are not logged, since there is no telling what they contain.
+func redactJSON(body []byte) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ""
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Sprintf("[non-JSON body, %d bytes]", len(body))
+	}
+
+	b, err := json.Marshal(redactDoc(doc))
+	if err != nil {
+		return fmt.Sprintf("[non-JSON body, %d bytes]", len(body))
+	}
+
+	return string(b)
+}
+
+func redactDoc(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if sensitiveFields[strings.ToLower(k)] {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redactDoc(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = redactDoc(val)
+		}
+	}
+
+	return doc
+}
+
+// cardNumberPattern matches digit runs as long as a card number, allowing
+// the spaces and dashes they are often printed with.
+var cardNumberPattern = regexp.MustCompile(`\d(?:[ -]?\d){12,18}`)
+
+// redactText masks card numbers in free text.
+func redactText(s string) string {
+	return cardNumberPattern.ReplaceAllString(s, redacted)
+}
+
+// redactQuery masks sensitive query parameters.
+func redactQuery(q url.Values) string {
+	for k := range q {
+		if sensitiveFields[strings.ToLower(k)] {
+			q[k] = []string{redacted}
+		}
+	}
+
+	s, err := url.QueryUnescape(q.Encode())
+	if err != nil {
+		return q.Encode()
+	}
+
+	return s
+}
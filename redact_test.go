@@ -0,0 +1,99 @@
+package liquidity
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) {
+	l.lines = append(l.lines, formatLog(msg, args))
+}
+
+func (l *recordingLogger) Error(msg string, args ...interface{}) {
+	l.lines = append(l.lines, formatLog("ERROR "+msg, args))
+}
+
+func TestRedactJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "masks card data",
+			body: `{"data":{"cardNumber":"5368989511270083","cvv2":"142","expiry":"2025-04-19","last4":"0083"}}`,
+			want: `{"data":{"cardNumber":"[REDACTED]","cvv2":"[REDACTED]","expiry":"[REDACTED]","last4":"0083"}}`,
+		},
+		{
+			name: "masks PII inside lists",
+			body: `[{"firstName":"Chijioke","userId":"d01a"}]`,
+			want: `[{"firstName":"[REDACTED]","userId":"d01a"}]`,
+		},
+		{
+			name: "keeps numbers exact",
+			body: `{"balance":0.10000000000000000001}`,
+			want: `{"balance":0.10000000000000000001}`,
+		},
+		{
+			name: "does not log bodies that are not JSON",
+			body: `<html>5368989511270083</html>`,
+			want: `[non-JSON body, 29 bytes]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactJSON([]byte(tt.body)); got != tt.want {
+				t.Errorf("redactJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_DebugLogging(t *testing.T) {
+	logger := &recordingLogger{}
+	c := newTestClient(&MockHttpClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			return jsonResponse(200, `{"message":"Ok","data":{"cardNumber":"5368989511270083","cvv2":"142","valid":"04/25"}}`), nil
+		},
+	})
+	c.SetAuth("sk_live_secret")
+	c.SetDebug(true)
+	c.SetLogger(logger)
+
+	if _, err := c.CreateCard(CreateCardData{UserId: "e08078bd", Expiry: "2025-04-18"}); err != nil {
+		t.Fatalf("CreateCard() error = %v", err)
+	}
+
+	out := strings.Join(logger.lines, "\n")
+	for _, secret := range []string{"5368989511270083", "142", "04/25", "2025-04-18", "sk_live_secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("logs contain %q:\n%s", secret, out)
+		}
+	}
+	for _, want := range []string{"method=POST", "path=/card/v1", "status=200", "latency="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("logs missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestClient_SetLoggerNil(t *testing.T) {
+	c := newTestClient(&MockHttpClient{
+		DoFunc: func(r *http.Request) (*http.Response, error) {
+			return nil, fmt.Errorf("boom")
+		},
+	})
+	c.SetDebug(true)
+	c.SetLogger(nil)
+	c.SetRetryPolicy(NoRetry)
+
+	if _, err := c.GetUser("d01a"); err == nil {
+		t.Errorf("GetUser() expected an error")
+	}
+}
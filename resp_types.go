@@ -1,6 +1,9 @@
 package liquidity
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type IntegratorResp struct {
 	Message string `json:"message"`
@@ -23,6 +26,7 @@ type CardResp struct {
 type CardsResp struct {
 	Message string `json:"message"`
 	Data    []D2   `json:"data"`
+	Lek     string `json:"lek,omitempty"`
 }
 
 type D2 struct {
@@ -33,7 +37,7 @@ type D2 struct {
 	CardNumber     string `json:"cardNumber,omitempty"`
 	Last4          string `json:"last4"`
 	TrackingNumber string `json:"trackingNumber"`
-	Balance        int    `json:"balance"`
+	Balance        Money  `json:"balance"`
 	Status         string `json:"status,omitempty"`
 	Currency       string `json:"currency"`
 	SingleUse      bool   `json:"singleUse"`
@@ -41,6 +45,18 @@ type D2 struct {
 	CreatedAt      string `json:"createdAt,omitempty"`
 }
 
+// UnmarshalJSON sets the currency of Balance from the card currency.
+func (d *D2) UnmarshalJSON(data []byte) error {
+	type plain D2
+	if err := json.Unmarshal(data, (*plain)(d)); err != nil {
+		return err
+	}
+
+	d.Balance.Currency = d.Currency
+
+	return nil
+}
+
 type DepositResp struct {
 	Message string `json:"message"`
 	Data    D3     `json:"data"`
@@ -49,11 +65,23 @@ type DepositResp struct {
 type D3 struct {
 	DepositId    string `json:"depositId,omitempty"`
 	U54DepositId string `json:"u54DepositId,omitempty"`
-	Amount       int    `json:"amount"`
+	Amount       Money  `json:"amount"`
 	Currency     string `json:"currency"`
 	Status       string `json:"status"`
 }
 
+// UnmarshalJSON sets the currency of Amount from the deposit currency.
+func (d *D3) UnmarshalJSON(data []byte) error {
+	type plain D3
+	if err := json.Unmarshal(data, (*plain)(d)); err != nil {
+		return err
+	}
+
+	d.Amount.Currency = d.Currency
+
+	return nil
+}
+
 type TransactionResp struct {
 	Message string `json:"message"`
 	Data    D4     `json:"data"`
@@ -62,6 +90,7 @@ type TransactionResp struct {
 type TransactionsResp struct {
 	Message string `json:"message"`
 	Data    []D4   `json:"data"`
+	Lek     string `json:"lek,omitempty"`
 }
 
 type D4 struct {
@@ -70,11 +99,11 @@ type D4 struct {
 	DebitCurrency            string `json:"debitCurrency"`
 	ConversionRate           int    `json:"conversionRate"`
 	CreditCurrency           string `json:"creditCurrency"`
-	TransactionBalanceBefore int    `json:"transactionBalanceBefore,omitempty"`
-	CardBalanceAfter         int    `json:"cardBalanceAfter,omitempty"`
+	TransactionBalanceBefore Money  `json:"transactionBalanceBefore,omitempty"`
+	CardBalanceAfter         Money  `json:"cardBalanceAfter,omitempty"`
 	CardId                   string `json:"cardId,omitempty"`
 	Type                     string `json:"type"`
-	Amount                   int    `json:"amount"`
+	Amount                   Money  `json:"amount"`
 	Currency                 string `json:"currency,omitempty"`
 	ErrorDescription         string `json:"errorDescription,omitempty"`
 	CreatedAt                string `json:"createdAt"`
@@ -82,6 +111,29 @@ type D4 struct {
 	AcquiringInstitutionCode string `json:"acquiringInstitutionCode"`
 }
 
+// UnmarshalJSON sets the currency of the amounts from the transaction
+// currency, falling back to the credited and then the debited currency.
+func (d *D4) UnmarshalJSON(data []byte) error {
+	type plain D4
+	if err := json.Unmarshal(data, (*plain)(d)); err != nil {
+		return err
+	}
+
+	currency := d.Currency
+	if currency == "" {
+		currency = d.CreditCurrency
+	}
+	if currency == "" {
+		currency = d.DebitCurrency
+	}
+
+	d.Amount.Currency = currency
+	d.TransactionBalanceBefore.Currency = currency
+	d.CardBalanceAfter.Currency = currency
+
+	return nil
+}
+
 type PostDepositResp struct {
 	Message string `json:"message"`
 	Data    D5     `json:"data"`
@@ -90,7 +142,7 @@ type PostDepositResp struct {
 type D5 struct {
 	U54DepositId string `json:"u54DepositId,omitempty"`
 	DepositId    string `json:"depositId,omitempty"`
-	Amount       int    `json:"amount"`
+	Amount       Money  `json:"amount"`
 	Currency     string `json:"currency"`
 	CreatedAt    string `json:"createdAt"`
 	Usd          Usd    `json:"usd"`
@@ -101,6 +153,18 @@ type D5 struct {
 	Usdt         Coin   `json:"usdt"`
 }
 
+// UnmarshalJSON sets the currency of Amount from the deposit currency.
+func (d *D5) UnmarshalJSON(data []byte) error {
+	type plain D5
+	if err := json.Unmarshal(data, (*plain)(d)); err != nil {
+		return err
+	}
+
+	d.Amount.Currency = d.Currency
+
+	return nil
+}
+
 type Usd struct {
 	AccountNumber string `json:"accountNumber"`
 	AccountName   string `json:"accountName"`
@@ -128,17 +192,29 @@ type D6 struct {
 	FloatId   string `json:"floatId"`
 	UpdatedAt string `json:"updatedAt"`
 	Currency  string `json:"currency"`
-	Balance   int    `json:"balance"`
+	Balance   Money  `json:"balance"`
 	IsDefault bool   `json:"isDefault"`
 }
 
+// UnmarshalJSON sets the currency of Balance from the float currency.
+func (d *D6) UnmarshalJSON(data []byte) error {
+	type plain D6
+	if err := json.Unmarshal(data, (*plain)(d)); err != nil {
+		return err
+	}
+
+	d.Balance.Currency = d.Currency
+
+	return nil
+}
+
 type t struct {
-	CardId string  `json:"cardId"`
-	Amount float64 `json:"amount"`
+	CardId string `json:"cardId"`
+	Amount Money  `json:"amount"`
 }
 
 type d struct {
-	Amount   int    `json:"amount"`
+	Amount   Money  `json:"amount"`
 	Currency string `json:"currency"`
 }
 
@@ -154,59 +230,134 @@ type s struct {
 type w struct {
 	Webhook string `json:"webhook"`
 }
-type getUserResp struct {
+
+// query string parameters, encoded with go-querystring
+
+type cardQuery struct {
+	Card           string `url:"card,omitempty"`
+	TrackingNumber string `url:"trackingNumber,omitempty"`
+}
+
+type cardsQuery struct {
+	User      string `url:"user,omitempty"`
+	Type      string `url:"type,omitempty"`
+	StartDate string `url:"startDate,omitempty"`
+	EndDate   string `url:"endDate,omitempty"`
+	Limit     int    `url:"limit,omitempty"`
+	Lek       string `url:"lek,omitempty"`
+}
+
+type transactionsQuery struct {
+	Card      string `url:"card,omitempty"`
+	StartDate string `url:"startDate,omitempty"`
+	EndDate   string `url:"endDate,omitempty"`
+	Limit     int    `url:"limit,omitempty"`
+	Lek       string `url:"lek,omitempty"`
+}
+
+type transactionQuery struct {
+	Transaction string `url:"transaction,omitempty"`
+}
+
+type depositQuery struct {
+	Deposit string `url:"deposit,omitempty"`
+}
+
+type floatsQuery struct {
+	Currencies []string `url:"currencies,omitempty"`
+}
+
+type floatQuery struct {
+	Currency string `url:"currency,omitempty"`
+}
+
+type userQuery struct {
+	UserID string `url:"userId,omitempty"`
+}
+
+type userDocQuery struct {
+	User string `url:"user,omitempty"`
+}
+
+// UserResp is returned by GetUser.
+type UserResp struct {
 	Message string `json:"message"`
-	Data    gur    `json:"data"`
-}
-
-type gur struct {
-	CreatedAt         time.Time `json:"createdAt,string"`
-	UpdatedAt         time.Time `json:"updatedAt,string"`
-	FirstName         string    `json:"firstName"`
-	LastName          string    `json:"lastName"`
-	UID               string    `json:"uid"`
-	KycCountry        string    `json:"kycCountry"`
-	Address           string    `json:"address"`
-	City              string    `json:"city"`
-	PostalCode        string    `json:"postalCode"`
-	PhysicalCardCount int       `json:"physicalCardCount"`
-	VirtualCardCount  int       `json:"virtualCardCount"`
-	SelfieUploaded    bool      `json:"selfieUploaded"`
-	IDUploaded        bool      `json:"idUploaded"`
-	OfacChecked       bool      `json:"ofacChecked"`
-	OfacFail          bool      `json:"ofacFail"`
-	Active            bool      `json:"active"`
-}
-
-type createUserResp struct {
-	Message string   `json:"message"`
-	Data    userResp `json:"data"`
-}
-type userResp struct {
-	UserID string `json:"userId"`
+	Data    User   `json:"data"`
+}
+
+// User is a card user: their profile, KYC status and card counts. The
+// fields of the embedded types can be used directly, as in u.Active.
+type User struct {
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt,string"`
+	UserProfile
+	KYCStatus
+	CardCounts
 }
 
-type getCardUserDocURLResp struct {
-	Message string     `json:"message"`
-	Data    docURLResp `json:"data"`
+// UserProfile is who a user is and where they live.
+type UserProfile struct {
+	FirstName  string `json:"firstName"`
+	LastName   string `json:"lastName"`
+	UID        string `json:"uid"`
+	KycCountry string `json:"kycCountry"`
+	Address    string `json:"address"`
+	City       string `json:"city"`
+	PostalCode string `json:"postalCode"`
 }
 
-type updateUserAddressResp struct {
+// KYCStatus tracks a user through KYC. A user can be issued cards once
+// Active is set.
+type KYCStatus struct {
+	SelfieUploaded bool `json:"selfieUploaded"`
+	IDUploaded     bool `json:"idUploaded"`
+	OfacChecked    bool `json:"ofacChecked"`
+	OfacFail       bool `json:"ofacFail"`
+	Active         bool `json:"active"`
+}
+
+// DocumentsUploaded reports whether both the selfie and the ID are in.
+func (k KYCStatus) DocumentsUploaded() bool {
+	return k.SelfieUploaded && k.IDUploaded
+}
+
+// CardCounts is how many cards a user has been issued.
+type CardCounts struct {
+	PhysicalCardCount int `json:"physicalCardCount"`
+	VirtualCardCount  int `json:"virtualCardCount"`
+}
+
+// Total returns the number of physical and virtual cards.
+func (c CardCounts) Total() int {
+	return c.PhysicalCardCount + c.VirtualCardCount
+}
+
+// CreateUserResp is returned by CreateUser.
+type CreateUserResp struct {
+	Message string      `json:"message"`
+	Data    CreatedUser `json:"data"`
+}
+
+// CreatedUser holds the ID assigned to a new user.
+type CreatedUser struct {
+	UserID string `json:"userId"`
+}
+
+// UpdateUserAddressResp is returned by UpdateUserAddress.
+type UpdateUserAddressResp struct {
 	Message string `json:"message"`
-	Data    struct {
-		Message string `json:"message"`
-	} `json:"data"`
+	Data    Resp   `json:"data"`
 }
-type validationError struct {
-	Code     string   `json:"code"`
-	Keys     []string `json:"keys"`
-	Expected string   `json:"expected"`
-	Received string   `json:"received"`
-	Path     []string `json:"path"`
-	Message  string   `json:"message"`
+
+// CardUserDocURLResp is returned by GetCardUserDocURL.
+type CardUserDocURLResp struct {
+	Message string          `json:"message"`
+	Data    CardUserDocURLs `json:"data"`
 }
 
-type docURLResp struct {
+// CardUserDocURLs are the pre-signed URLs a user's KYC documents are
+// uploaded to. See UploadSelfie and UploadID.
+type CardUserDocURLs struct {
 	SelfieUploadURL string `json:"selfieUploadUrl"`
 	IDUploadURL     string `json:"idUploadUrl"`
 	UID             string `json:"uid"`
@@ -0,0 +1,306 @@
+// Package spend guards TopUp and Debit with local spending rules. A Client
+// wraps a *liquidity.Client and rejects a call that breaks a rule before
+// anything is sent to One Liquidity.
+package spend
+
+import (
+	"context"
+	er "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// Rule names a spending rule.
+type Rule string
+
+const (
+	// BlockedCard rejects calls for a card in Rules.BlockedCards.
+	BlockedCard Rule = "blocked card"
+	// CurrencyNotAllowed rejects amounts outside Rules.AllowedCurrencies
+	// or in a currency other than a limit's.
+	CurrencyNotAllowed Rule = "currency not allowed"
+	// MaxPerCall rejects an amount above Rules.MaxPerCall.
+	MaxPerCall Rule = "max per call"
+	// DailyLimit rejects a call that would take a card past
+	// Rules.DailyLimit for the day.
+	DailyLimit Rule = "daily limit"
+	// MonthlyLimit rejects a call that would take a card past
+	// Rules.MonthlyLimit for the month.
+	MonthlyLimit Rule = "monthly limit"
+)
+
+// Rules limits one kind of call. Zero amounts and empty lists mean no limit.
+//
+// A limit with a currency rejects amounts in any other currency, including
+// amounts without one. A limit without a currency counts amounts in every
+// currency together.
+type Rules struct {
+	MaxPerCall   liquidity.Money
+	DailyLimit   liquidity.Money
+	MonthlyLimit liquidity.Money
+	// AllowedCurrencies lists the currencies an amount may be in. An amount
+	// without a currency is rejected when it is set.
+	AllowedCurrencies []string
+	BlockedCards      []string
+}
+
+// Violation is the error returned for a call that breaks a rule.
+type Violation struct {
+	Rule   Rule
+	Op     string
+	CardID string
+	Amount liquidity.Money
+	// Limit is the limit that was broken, and Used how much of a daily or
+	// monthly limit had already been used.
+	Limit liquidity.Money
+	Used  liquidity.Money
+}
+
+func (v *Violation) Error() string {
+	msg := fmt.Sprintf("spend: %s of %s to card %s rejected: %s", v.Op, v.Amount, v.CardID, v.Rule)
+
+	switch v.Rule {
+	case MaxPerCall:
+		msg += fmt.Sprintf(" of %s", v.Limit.Amount())
+	case DailyLimit, MonthlyLimit:
+		msg += fmt.Sprintf(" of %s, %s used", v.Limit.Amount(), v.Used.Amount())
+	}
+
+	return msg
+}
+
+// IsViolation reports whether err is a *Violation.
+func IsViolation(err error) bool {
+	var v *Violation
+	return er.As(err, &v)
+}
+
+// Client is a *liquidity.Client whose TopUp and Debit calls are checked
+// against TopUpRules and DebitRules. Every other method is passed through.
+//
+// A call that reaches One Liquidity counts towards the daily and monthly
+// limits unless it is rejected with a 4xx. A call that times out or fails
+// with a 5xx still counts, since the money may have moved. Retrying it with
+// the same liquidity.IdempotencyKey does not count it again.
+type Client struct {
+	*liquidity.Client
+
+	Store      Store
+	TopUpRules Rules
+	DebitRules Rules
+
+	// Location sets where days and months start. It defaults to UTC.
+	Location *time.Location
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// New wraps client, keeping the limit totals in store. Set the rules on the
+// returned Client before use.
+func New(client *liquidity.Client, store Store) *Client {
+	return &Client{Client: client, Store: store}
+}
+
+// TopUp checks the top-up against TopUpRules before making it.
+func (c *Client) TopUp(cardId string, amount liquidity.Money, opts ...liquidity.CallOption) (liquidity.CardResp, error) {
+	return c.TopUpContext(context.Background(), cardId, amount, opts...)
+}
+
+// TopUpContext is like TopUp but uses ctx for the request.
+func (c *Client) TopUpContext(ctx context.Context, cardId string, amount liquidity.Money, opts ...liquidity.CallOption) (liquidity.CardResp, error) {
+	return c.guard(ctx, "topup", c.TopUpRules, cardId, amount, opts, c.Client.TopUpContext)
+}
+
+// Debit checks the debit against DebitRules before making it.
+func (c *Client) Debit(cardId string, amount liquidity.Money, opts ...liquidity.CallOption) (liquidity.CardResp, error) {
+	return c.DebitContext(context.Background(), cardId, amount, opts...)
+}
+
+// DebitContext is like Debit but uses ctx for the request.
+func (c *Client) DebitContext(ctx context.Context, cardId string, amount liquidity.Money, opts ...liquidity.CallOption) (liquidity.CardResp, error) {
+	return c.guard(ctx, "debit", c.DebitRules, cardId, amount, opts, c.Client.DebitContext)
+}
+
+type call func(ctx context.Context, cardId string, amount liquidity.Money, opts ...liquidity.CallOption) (liquidity.CardResp, error)
+
+func (c *Client) guard(ctx context.Context, op string, rules Rules, cardId string, amount liquidity.Money, opts []liquidity.CallOption, do call) (liquidity.CardResp, error) {
+	// non-positive amounts are rejected by the client; counting them would
+	// free up the limits
+	if amount.Sign() <= 0 {
+		return do(ctx, cardId, amount, opts...)
+	}
+
+	if err := check(op, rules, cardId, amount); err != nil {
+		return liquidity.CardResp{}, err
+	}
+
+	release, err := c.reserve(ctx, op, rules, cardId, amount, liquidity.IdempotencyKeyOf(opts...))
+	if err != nil {
+		return liquidity.CardResp{}, err
+	}
+
+	res, err := do(ctx, cardId, amount, opts...)
+
+	if rejected(err) {
+		release(ctx)
+	}
+
+	return res, err
+}
+
+// rejected reports whether err shows the call was definitely not carried
+// out. Anything else, such as a timeout or a 5xx, may have moved the money.
+func rejected(err error) bool {
+	var apiErr *liquidity.APIError
+	if er.As(err, &apiErr) {
+		return apiErr.StatusCode >= 400 && apiErr.StatusCode < 500
+	}
+
+	return er.Is(err, liquidity.ErrIdempotencyKeyReused)
+}
+
+// check applies the rules that do not need a counter.
+func check(op string, rules Rules, cardId string, amount liquidity.Money) error {
+	violation := func(rule Rule, limit liquidity.Money) error {
+		return &Violation{Rule: rule, Op: op, CardID: cardId, Amount: amount, Limit: limit}
+	}
+
+	for _, blocked := range rules.BlockedCards {
+		if blocked == cardId {
+			return violation(BlockedCard, liquidity.Money{})
+		}
+	}
+
+	if len(rules.AllowedCurrencies) > 0 && !contains(rules.AllowedCurrencies, amount.Currency) {
+		return violation(CurrencyNotAllowed, liquidity.Money{})
+	}
+
+	for _, limit := range []liquidity.Money{rules.MaxPerCall, rules.DailyLimit, rules.MonthlyLimit} {
+		if !limit.IsZero() && limit.Currency != "" && !strings.EqualFold(limit.Currency, amount.Currency) {
+			return violation(CurrencyNotAllowed, limit)
+		}
+	}
+
+	if !rules.MaxPerCall.IsZero() && amount.Cmp(rules.MaxPerCall) > 0 {
+		return violation(MaxPerCall, rules.MaxPerCall)
+	}
+
+	return nil
+}
+
+func contains(currencies []string, currency string) bool {
+	for _, c := range currencies {
+		if currency != "" && strings.EqualFold(c, currency) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// period is a daily or monthly counter.
+type period struct {
+	rule    Rule
+	limit   liquidity.Money
+	key     string
+	expires time.Time
+}
+
+func (c *Client) periods(op string, rules Rules, cardId string) []period {
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+
+	loc := c.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	t := now().In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	prefix := op + ":" + cardId + ":"
+
+	var ps []period
+	if !rules.DailyLimit.IsZero() {
+		ps = append(ps, period{DailyLimit, rules.DailyLimit, prefix + day.Format("2006-01-02"), day.AddDate(0, 0, 1)})
+	}
+	if !rules.MonthlyLimit.IsZero() {
+		ps = append(ps, period{MonthlyLimit, rules.MonthlyLimit, prefix + month.Format("2006-01"), month.AddDate(0, 1, 0)})
+	}
+
+	return ps
+}
+
+// one marks an idempotency key as counted.
+var one = liquidity.NewMoney(1, "")
+
+// reserve counts amount towards the daily and monthly limits, undoing it if
+// a limit would be passed. The returned func gives the amount back. A call
+// with an idempotency key that was already counted is not counted again.
+func (c *Client) reserve(ctx context.Context, op string, rules Rules, cardId string, amount liquidity.Money, idempotencyKey string) (func(ctx context.Context), error) {
+	periods := c.periods(op, rules, cardId)
+	if len(periods) == 0 {
+		return func(ctx context.Context) {}, nil
+	}
+
+	var reserved []period
+	var marked bool
+
+	keyCounter := "idempotency:" + idempotencyKey
+	keyExpires := periods[len(periods)-1].expires
+
+	release := func(ctx context.Context) {
+		for _, p := range reserved {
+			c.Store.Add(ctx, p.key, counted(amount, p.limit).Neg(), p.expires)
+		}
+		if marked {
+			c.Store.Add(ctx, keyCounter, one.Neg(), keyExpires)
+		}
+	}
+
+	if idempotencyKey != "" {
+		n, err := c.Store.Add(ctx, keyCounter, one, keyExpires)
+		if err != nil {
+			return nil, err
+		}
+
+		if n.Cmp(one) > 0 {
+			// a retry of a call already counted; the first attempt keeps
+			// its count whatever this one returns
+			c.Store.Add(ctx, keyCounter, one.Neg(), keyExpires)
+			return func(ctx context.Context) {}, nil
+		}
+		marked = true
+	}
+
+	for _, p := range periods {
+		total, err := c.Store.Add(ctx, p.key, counted(amount, p.limit), p.expires)
+		if err != nil {
+			release(ctx)
+			return nil, err
+		}
+
+		reserved = append(reserved, p)
+
+		if total.Cmp(p.limit) > 0 {
+			release(ctx)
+
+			used, _ := total.Sub(counted(amount, p.limit))
+			return nil, &Violation{Rule: p.rule, Op: op, CardID: cardId, Amount: amount, Limit: p.limit, Used: used}
+		}
+	}
+
+	return release, nil
+}
+
+// counted is amount as added to a counter for limit, in the limit's
+// currency. check has already rejected amounts in any other.
+func counted(amount, limit liquidity.Money) liquidity.Money {
+	amount.Currency = limit.Currency
+	return amount
+}
@@ -0,0 +1,193 @@
+package spend
+
+import (
+	"context"
+	er "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+	"github.com/bushaHQ/one-liquidity-go/liquiditytest"
+)
+
+func usd(s string) liquidity.Money {
+	return liquidity.MustParseMoney(s, "USD")
+}
+
+func setup(t *testing.T) (*liquiditytest.Server, *Client, string) {
+	srv := liquiditytest.NewServer()
+	t.Cleanup(srv.Close)
+
+	srv.SetFloat(usd("100000"))
+	userID := srv.AddUser(liquidity.CreateUserData{FirstName: "Ada", UID: "u-1"})
+
+	inner := srv.Client()
+	card, err := inner.CreateCard(liquidity.CreateCardData{UserId: userID, Expiry: "2099-01-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return srv, New(inner, NewMemoryStore()), card.Data.CardId
+}
+
+func TestClient_TopUpRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  Rules
+		card   string
+		amount liquidity.Money
+		want   Rule
+	}{
+		{"allowed", Rules{MaxPerCall: usd("100"), AllowedCurrencies: []string{"usd"}}, "", usd("100"), ""},
+		{"max per call", Rules{MaxPerCall: usd("100")}, "", usd("100.01"), MaxPerCall},
+		{"currency", Rules{AllowedCurrencies: []string{"USD"}}, "", liquidity.MustParseMoney("1", "BTC"), CurrencyNotAllowed},
+		{"no currency", Rules{AllowedCurrencies: []string{"USD"}}, "", liquidity.MustParseMoney("1", ""), CurrencyNotAllowed},
+		{"blocked card", Rules{BlockedCards: []string{"blocked"}}, "blocked", usd("1"), BlockedCard},
+		{"daily limit", Rules{DailyLimit: usd("50")}, "", usd("50.01"), DailyLimit},
+		{"limit currency", Rules{DailyLimit: usd("50")}, "", liquidity.MustParseMoney("1", "EUR"), CurrencyNotAllowed},
+		{"no currency under a limit currency", Rules{MaxPerCall: usd("50")}, "", liquidity.MustParseMoney("1", ""), CurrencyNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, cl, cardID := setup(t)
+			if tt.card != "" {
+				cardID = tt.card
+			}
+			cl.TopUpRules = tt.rules
+
+			_, err := cl.TopUp(cardID, tt.amount)
+
+			calls := srv.Calls(http.MethodPatch, "/card/v1/credit/balance")
+			if tt.want == "" {
+				if err != nil || calls != 1 {
+					t.Fatalf("TopUp() error = %v after %d calls, want success", err, calls)
+				}
+				return
+			}
+
+			var v *Violation
+			if !er.As(err, &v) || v.Rule != tt.want {
+				t.Fatalf("TopUp() error = %v, want a %s violation", err, tt.want)
+			}
+			if calls != 0 {
+				t.Errorf("TopUp() made %d calls, want none", calls)
+			}
+		})
+	}
+}
+
+func TestClient_Limits(t *testing.T) {
+	srv, cl, cardID := setup(t)
+
+	now := time.Date(2023, 3, 31, 12, 0, 0, 0, time.UTC)
+	cl.Now = func() time.Time { return now }
+	cl.Store.(*MemoryStore).now = cl.Now
+	cl.TopUpRules = Rules{DailyLimit: usd("100"), MonthlyLimit: usd("150")}
+	cl.DebitRules = Rules{MaxPerCall: usd("10")}
+
+	if _, err := cl.TopUp(cardID, usd("60")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cl.Debit(cardID, usd("10")); err != nil {
+		t.Fatalf("Debit() error = %v; debits have their own rules", err)
+	}
+
+	_, err := cl.TopUp(cardID, usd("50"))
+	var v *Violation
+	if !er.As(err, &v) || v.Rule != DailyLimit || !v.Used.Equal(usd("60")) {
+		t.Fatalf("TopUp() error = %v, want the daily limit with 60.00 used", err)
+	}
+
+	if _, err := cl.TopUp(cardID, usd("40")); err != nil {
+		t.Fatalf("TopUp() error = %v; a rejected call must not use the limit", err)
+	}
+
+	now = now.AddDate(0, 0, -1)
+	if _, err := cl.TopUp(cardID, usd("60")); !IsViolation(err) {
+		t.Fatalf("TopUp() error = %v, want the monthly limit", err)
+	}
+
+	now = now.AddDate(0, 0, 2)
+	if _, err := cl.TopUp(cardID, usd("100")); err != nil {
+		t.Fatalf("TopUp() error = %v; limits reset in April", err)
+	}
+
+	if got := srv.Calls(http.MethodPatch, "/card/v1/credit/balance"); got != 3 {
+		t.Errorf("made %d top-ups, want 3", got)
+	}
+}
+
+func TestClient_APIErrorReleasesLimit(t *testing.T) {
+	srv, cl, cardID := setup(t)
+	cl.DebitRules = Rules{DailyLimit: usd("10")}
+
+	if _, err := cl.Debit(cardID, usd("10")); !liquidity.IsInsufficientFunds(err) {
+		t.Fatalf("Debit() error = %v, want insufficient funds", err)
+	}
+
+	if _, err := cl.TopUp(cardID, usd("10")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cl.DebitContext(context.Background(), cardID, usd("10")); err != nil {
+		t.Fatalf("Debit() error = %v; the failed debit should not count", err)
+	}
+
+	if got := srv.Calls(http.MethodPatch, "/card/v1/debit/balance"); got != 2 {
+		t.Errorf("made %d debits, want 2", got)
+	}
+}
+
+func TestClient_CurrencyLabelsShareLimits(t *testing.T) {
+	_, cl, cardID := setup(t)
+	cl.TopUpRules = Rules{DailyLimit: liquidity.MustParseMoney("100", "")}
+
+	if _, err := cl.TopUp(cardID, usd("90")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, currency := range []string{"", "EUR", "GBP"} {
+		if _, err := cl.TopUp(cardID, liquidity.MustParseMoney("90", currency)); !IsViolation(err) {
+			t.Errorf("TopUp() in %q error = %v, want the daily limit", currency, err)
+		}
+	}
+}
+
+func TestClient_ServerErrorKeepsLimit(t *testing.T) {
+	srv, cl, cardID := setup(t)
+	cl.TopUpRules = Rules{DailyLimit: usd("10")}
+
+	srv.AddRule(liquiditytest.Rule{Method: http.MethodPatch, Path: "/card/v1/credit/balance", Nth: []int{1}, Fault: liquiditytest.LostResponse()})
+
+	if _, err := cl.TopUp(cardID, usd("10")); err == nil {
+		t.Fatal("TopUp() succeeded despite the lost response")
+	}
+
+	if _, err := cl.TopUp(cardID, usd("10")); !IsViolation(err) {
+		t.Errorf("TopUp() error = %v; a 5xx may have moved the money and must keep counting", err)
+	}
+}
+
+func TestClient_RetryWithKeyCountsOnce(t *testing.T) {
+	srv, cl, cardID := setup(t)
+	cl.TopUpRules = Rules{DailyLimit: usd("15")}
+
+	srv.AddRule(liquiditytest.Rule{Method: http.MethodPatch, Path: "/card/v1/credit/balance", Nth: []int{1}, Fault: liquiditytest.LostResponse()})
+
+	key := liquidity.IdempotencyKey("topup-1")
+	if _, err := cl.TopUp(cardID, usd("10"), key); err == nil {
+		t.Fatal("TopUp() succeeded despite the lost response")
+	}
+
+	if _, err := cl.TopUp(cardID, usd("10"), key); err != nil {
+		t.Fatalf("retried TopUp() error = %v, want the first result", err)
+	}
+	if card, _ := srv.Card(cardID); !card.Balance.Equal(usd("10")) {
+		t.Errorf("balance = %v, want 10.00 USD", card.Balance)
+	}
+
+	if _, err := cl.TopUp(cardID, usd("10"), liquidity.IdempotencyKey("topup-2")); !IsViolation(err) {
+		t.Errorf("TopUp() with a new key error = %v, want the daily limit with 10.00 used", err)
+	}
+}
@@ -0,0 +1,62 @@
+package spend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// Store keeps the running totals behind daily and monthly limits, and a
+// counter per idempotency key already counted towards them.
+// Implementations must be safe for concurrent use, and Add must be atomic so
+// that two calls racing for the last of a limit cannot both get it.
+type Store interface {
+	// Add adds amount, which may be negative, to the counter named key and
+	// returns the new total. A counter that does not exist starts at zero.
+	// The counter is no longer needed after expires.
+	Add(ctx context.Context, key string, amount liquidity.Money, expires time.Time) (liquidity.Money, error)
+}
+
+type counter struct {
+	total   liquidity.Money
+	expires time.Time
+}
+
+// MemoryStore is a Store held in memory. Totals are lost on restart, so share
+// a persistent Store between processes in production.
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]counter
+	now      func() time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]counter), now: time.Now}
+}
+
+// Add implements Store.
+func (s *MemoryStore) Add(_ context.Context, key string, amount liquidity.Money, expires time.Time) (liquidity.Money, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, c := range s.counters {
+		if now.After(c.expires) {
+			delete(s.counters, k)
+		}
+	}
+
+	c := s.counters[key]
+
+	total, err := c.total.Add(amount)
+	if err != nil {
+		return liquidity.Money{}, err
+	}
+
+	s.counters[key] = counter{total: total, expires: expires}
+
+	return total, nil
+}
@@ -0,0 +1,248 @@
+package liquidity
+
+import (
+	"bytes"
+	"context"
+	er "errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// MaxDocumentSize is the largest KYC document UploadSelfie and UploadID
+// accept.
+const MaxDocumentSize = 10 << 20
+
+// documentTypes are the content types accepted for KYC documents.
+var documentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"application/pdf": true,
+}
+
+// how long UploadSelfie and UploadID wait for GetUser to show the document
+var (
+	uploadConfirmAttempts = 5
+	uploadConfirmDelay    = time.Second
+)
+
+// Errors returned by UploadSelfie and UploadID.
+var (
+	ErrDocumentType       = er.New("liquidity: document must be a JPEG, PNG or PDF")
+	ErrDocumentSize       = fmt.Errorf("liquidity: document must be between 1 byte and %d bytes", MaxDocumentSize)
+	ErrUploadNotConfirmed = er.New("liquidity: upload not confirmed by the user's KYC flags")
+)
+
+// UploadProgress is called as a document upload is sent, with the bytes
+// sent so far and the total.
+type UploadProgress func(sent, total int64)
+
+// UploadOption configures a single UploadSelfie or UploadID call.
+type UploadOption func(o *uploadOptions)
+
+type uploadOptions struct {
+	progress UploadProgress
+}
+
+// OnProgress reports the upload's progress to fn.
+func OnProgress(fn UploadProgress) UploadOption {
+	return func(o *uploadOptions) {
+		o.progress = fn
+	}
+}
+
+// UploadSelfie uploads the user's selfie to the URL from GetCardUserDocURL
+// and waits for GetUser to show it. contentType must be image/jpeg,
+// image/png or application/pdf and match the data.
+func (cl *Client) UploadSelfie(ctx context.Context, userID string, r io.Reader, contentType string, opts ...UploadOption) error {
+	return cl.uploadDocument(ctx, userID, "selfie", r, contentType, opts)
+}
+
+// UploadID is like UploadSelfie for the user's ID document.
+func (cl *Client) UploadID(ctx context.Context, userID string, r io.Reader, contentType string, opts ...UploadOption) error {
+	return cl.uploadDocument(ctx, userID, "id", r, contentType, opts)
+}
+
+func (cl *Client) uploadDocument(ctx context.Context, userID string, kind string, r io.Reader, contentType string, opts []UploadOption) error {
+	var o uploadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !documentTypes[mediaType] {
+		return fmt.Errorf("%w, got %q", ErrDocumentType, contentType)
+	}
+
+	body, size, err := documentBody(r, mediaType)
+	if err != nil {
+		return err
+	}
+
+	urls, err := cl.GetCardUserDocURLContext(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	url := urls.Data.SelfieUploadURL
+	if kind == "id" {
+		url = urls.Data.IDUploadURL
+	}
+
+	if o.progress != nil {
+		body = &progressReader{r: body, total: size, fn: o.progress}
+	}
+
+	if err := cl.put(ctx, url, body, size, mediaType); err != nil {
+		return err
+	}
+
+	return cl.confirmUpload(ctx, userID, kind)
+}
+
+// documentBody checks the size and type of a document and returns a reader
+// for all of it. Readers that cannot report their size are read into memory.
+func documentBody(r io.Reader, mediaType string) (io.Reader, int64, error) {
+	size, ok := readerSize(r)
+	if !ok {
+		data, err := io.ReadAll(io.LimitReader(r, MaxDocumentSize+1))
+		if err != nil {
+			return nil, 0, err
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	if size <= 0 || size > MaxDocumentSize {
+		return nil, 0, fmt.Errorf("%w, got %d", ErrDocumentSize, size)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, 0, err
+	}
+	head = head[:n]
+
+	if detected := http.DetectContentType(head); detected != mediaType {
+		return nil, 0, fmt.Errorf("%w: content is %s, not %s", ErrDocumentType, detected, mediaType)
+	}
+
+	return io.MultiReader(bytes.NewReader(head), r), size, nil
+}
+
+// readerSize returns the bytes left in r, if r can tell.
+func readerSize(r io.Reader) (int64, bool) {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len()), true
+	case io.Seeker:
+		cur, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, false
+		}
+		if _, err := v.Seek(cur, io.SeekStart); err != nil {
+			return 0, false
+		}
+		return end - cur, true
+	}
+
+	return 0, false
+}
+
+// put streams body to a pre-signed URL. The URL carries its own credentials,
+// so the API key is not sent and the query is not logged.
+func (cl *Client) put(ctx context.Context, url string, body io.Reader, size int64, contentType string) error {
+	c := cl.config()
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	if c.debug {
+		c.logger.Debug("upload", "path", req.URL.Path, "size", size, "type", contentType)
+	}
+
+	start := time.Now()
+
+	r, err := c.httpClient.Do(req)
+	if err != nil {
+		if c.debug {
+			c.logger.Error("upload failed", "path", req.URL.Path, "latency", time.Since(start), "error", err)
+		}
+		return err
+	}
+	defer r.Body.Close()
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if c.debug {
+		c.logger.Debug("upload response", "path", req.URL.Path, "status", r.StatusCode, "latency", time.Since(start))
+	}
+
+	if r.StatusCode < 200 || r.StatusCode >= 300 {
+		return newAPIError(r, data)
+	}
+
+	return nil
+}
+
+// confirmUpload waits for the user's KYC flags to show the document.
+func (cl *Client) confirmUpload(ctx context.Context, userID string, kind string) error {
+	for attempt := 1; ; attempt++ {
+		u, err := cl.GetUserContext(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		uploaded := u.Data.SelfieUploaded
+		if kind == "id" {
+			uploaded = u.Data.IDUploaded
+		}
+
+		if uploaded {
+			return nil
+		}
+
+		if attempt == uploadConfirmAttempts {
+			return fmt.Errorf("%w: %s of user %s", ErrUploadNotConfirmed, kind, userID)
+		}
+
+		if err := sleep(ctx, uploadConfirmDelay); err != nil {
+			return err
+		}
+	}
+}
+
+// progressReader reports how much of a body has been read.
+type progressReader struct {
+	r     io.Reader
+	sent  int64
+	total int64
+	fn    UploadProgress
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		p.fn(p.sent, p.total)
+	}
+
+	return n, err
+}
@@ -0,0 +1,164 @@
+package liquidity
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	pngData  = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 1024)...)
+	jpegData = append([]byte("\xff\xd8\xff\xe0"), bytes.Repeat([]byte{0}, 100)...)
+)
+
+// uploadServer fakes the document URL, upload and user endpoints.
+type uploadServer struct {
+	puts     map[string][]byte
+	headers  map[string]http.Header
+	confirms bool
+}
+
+func (s *uploadServer) Do(r *http.Request) (*http.Response, error) {
+	switch {
+	case r.URL.Path == "/card/v1/user/documentation/urls":
+		return jsonResponse(200, `{"message":"Ok","data":{"selfieUploadUrl":"https://uploads.example/u1/selfie?X-Amz-Signature=abc","idUploadUrl":"https://uploads.example/u1/id?X-Amz-Signature=def","uid":"u-1"}}`), nil
+	case r.URL.Host == "uploads.example" && r.Method == http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		if int64(len(body)) != r.ContentLength {
+			return jsonResponse(400, `{"message":"length mismatch"}`), nil
+		}
+		s.puts[r.URL.Path] = body
+		s.headers[r.URL.Path] = r.Header
+		return jsonResponse(200, ""), nil
+	case r.URL.Path == "/card/v1/user":
+		selfie := s.confirms && s.puts["/u1/selfie"] != nil
+		id := s.confirms && s.puts["/u1/id"] != nil
+		return jsonResponse(200, `{"message":"Ok","data":{"selfieUploaded":`+boolString(selfie)+`,"idUploaded":`+boolString(id)+`}}`), nil
+	}
+
+	return jsonResponse(404, `{"message":"Not Found"}`), nil
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+func TestClient_UploadDocuments(t *testing.T) {
+	delay := uploadConfirmDelay
+	uploadConfirmDelay = time.Millisecond
+	t.Cleanup(func() { uploadConfirmDelay = delay })
+
+	tests := []struct {
+		name        string
+		upload      func(c *Client, progress UploadOption) error
+		noConfirm   bool
+		wantErr     error
+		wantPath    string
+		wantType    string
+		wantPutSize int
+	}{
+		{
+			name: "uploads a selfie",
+			upload: func(c *Client, progress UploadOption) error {
+				return c.UploadSelfie(context.Background(), "u1", bytes.NewReader(pngData), "image/png", progress)
+			},
+			wantPath:    "/u1/selfie",
+			wantType:    "image/png",
+			wantPutSize: len(pngData),
+		},
+		{
+			name: "uploads an ID from a reader of unknown size",
+			upload: func(c *Client, progress UploadOption) error {
+				return c.UploadID(context.Background(), "u1", io.MultiReader(bytes.NewReader(jpegData)), "image/jpeg", progress)
+			},
+			wantPath:    "/u1/id",
+			wantType:    "image/jpeg",
+			wantPutSize: len(jpegData),
+		},
+		{
+			name: "rejects an unsupported type",
+			upload: func(c *Client, progress UploadOption) error {
+				return c.UploadID(context.Background(), "u1", strings.NewReader("hello"), "text/plain", progress)
+			},
+			wantErr: ErrDocumentType,
+		},
+		{
+			name: "rejects content that does not match its type",
+			upload: func(c *Client, progress UploadOption) error {
+				return c.UploadSelfie(context.Background(), "u1", bytes.NewReader(pngData), "application/pdf", progress)
+			},
+			wantErr: ErrDocumentType,
+		},
+		{
+			name: "rejects an empty document",
+			upload: func(c *Client, progress UploadOption) error {
+				return c.UploadSelfie(context.Background(), "u1", strings.NewReader(""), "image/png", progress)
+			},
+			wantErr: ErrDocumentSize,
+		},
+		{
+			name: "rejects a document over the limit",
+			upload: func(c *Client, progress UploadOption) error {
+				big := io.MultiReader(bytes.NewReader(pngData), bytes.NewReader(make([]byte, MaxDocumentSize)))
+				return c.UploadSelfie(context.Background(), "u1", big, "image/png", progress)
+			},
+			wantErr: ErrDocumentSize,
+		},
+		{
+			name: "fails when the flags never flip",
+			upload: func(c *Client, progress UploadOption) error {
+				return c.UploadSelfie(context.Background(), "u1", bytes.NewReader(pngData), "image/png", progress)
+			},
+			noConfirm:   true,
+			wantErr:     ErrUploadNotConfirmed,
+			wantPath:    "/u1/selfie",
+			wantType:    "image/png",
+			wantPutSize: len(pngData),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &uploadServer{puts: map[string][]byte{}, headers: map[string]http.Header{}, confirms: !tt.noConfirm}
+
+			c := NewClient()
+			c.SetDebug(false)
+			c.SetHTTPClient(srv)
+
+			var sent, total int64
+			err := tt.upload(c, OnProgress(func(s, t int64) { sent, total = s, t }))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("upload error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantPath == "" {
+				if len(srv.puts) != 0 {
+					t.Errorf("uploaded %d documents, want none", len(srv.puts))
+				}
+				return
+			}
+
+			if got := len(srv.puts[tt.wantPath]); got != tt.wantPutSize {
+				t.Errorf("uploaded %d bytes to %s, want %d", got, tt.wantPath, tt.wantPutSize)
+			}
+
+			h := srv.headers[tt.wantPath]
+			if h.Get("Content-Type") != tt.wantType || h.Get("Authorization") != "" {
+				t.Errorf("upload headers = %v, want %s and no Authorization", h, tt.wantType)
+			}
+
+			if sent != int64(tt.wantPutSize) || total != int64(tt.wantPutSize) {
+				t.Errorf("progress = %d/%d, want %d/%d", sent, total, tt.wantPutSize, tt.wantPutSize)
+			}
+		})
+	}
+}
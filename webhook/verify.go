@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	er "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SignatureHeader carries the signature of a webhook request in the form
+// "t=<unix seconds>,v1=<hex HMAC-SHA256>". The HMAC is computed with the
+// shared secret over "<t>.<body>". Several v1 entries may be present while a
+// secret is being rotated.
+const SignatureHeader = "X-Liquidity-Signature"
+
+// DefaultTolerance is how far a signature timestamp may be from the current
+// time before the request is rejected.
+const DefaultTolerance = 5 * time.Minute
+
+// Verification errors.
+var (
+	ErrMissingSignature = er.New("webhook: missing signature")
+	ErrInvalidSignature = er.New("webhook: invalid signature")
+	ErrTimestampExpired = er.New("webhook: timestamp outside tolerance")
+)
+
+// Verifier checks that webhook requests were signed with the shared secret
+// and are recent.
+type Verifier struct {
+	secret []byte
+
+	// Tolerance bounds the age of a signature, and how far in the future it
+	// may be. Zero means DefaultTolerance.
+	Tolerance time.Duration
+	// Store remembers processed events so replays are not dispatched again.
+	// Nil disables replay protection.
+	Store SeenStore
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewVerifier returns a Verifier for secret that keeps processed event IDs in
+// memory.
+func NewVerifier(secret string) *Verifier {
+	return &Verifier{
+		secret: []byte(secret),
+		Store:  NewMemorySeenStore(),
+	}
+}
+
+// Sign returns the SignatureHeader value for body signed with secret at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac([]byte(secret), ts, body))
+}
+
+func mac(secret []byte, ts string, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Verify checks header, the SignatureHeader value, against body. It returns
+// the signature timestamp.
+func (v *Verifier) Verify(header string, body []byte) (time.Time, error) {
+	if header == "" {
+		return time.Time{}, ErrMissingSignature
+	}
+
+	var ts string
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			if sig, err := hex.DecodeString(kv[1]); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return time.Time{}, fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+
+	expected := mac(v.secret, ts, body)
+	valid := false
+	for _, sig := range sigs {
+		// check every entry so timing does not reveal which one matched
+		if hmac.Equal(sig, expected) {
+			valid = true
+		}
+	}
+
+	if !valid {
+		return time.Time{}, ErrInvalidSignature
+	}
+
+	signed := time.Unix(unix, 0)
+	if age := v.now().Sub(signed); age > v.tolerance() || age < -v.tolerance() {
+		return time.Time{}, ErrTimestampExpired
+	}
+
+	return signed, nil
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+
+	return time.Now()
+}
+
+func (v *Verifier) tolerance() time.Duration {
+	if v.Tolerance > 0 {
+		return v.Tolerance
+	}
+
+	return DefaultTolerance
+}
+
+// SeenStore remembers which events have been claimed for processing.
+// Implementations must be safe for concurrent use.
+type SeenStore interface {
+	// Claim records id until expires unless it is already recorded and not
+	// yet expired. It reports whether the caller got the claim, and must be
+	// atomic so two concurrent deliveries cannot both get it.
+	Claim(id string, expires time.Time) (bool, error)
+	// Release forgets a claimed id, so a later delivery can claim it.
+	Release(id string) error
+}
+
+// sweepInterval is the least time between two sweeps of expired ids in a
+// MemorySeenStore.
+const sweepInterval = time.Minute
+
+// MemorySeenStore is an in-process SeenStore.
+type MemorySeenStore struct {
+	mu    sync.Mutex
+	ids   map[string]time.Time
+	swept time.Time
+}
+
+// NewMemorySeenStore returns an empty MemorySeenStore.
+func NewMemorySeenStore() *MemorySeenStore {
+	return &MemorySeenStore{ids: make(map[string]time.Time)}
+}
+
+// Claim implements SeenStore.
+func (s *MemorySeenStore) Claim(id string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.swept) >= sweepInterval {
+		for k, exp := range s.ids {
+			if now.After(exp) {
+				delete(s.ids, k)
+			}
+		}
+		s.swept = now
+	}
+
+	if exp, ok := s.ids[id]; ok && !now.After(exp) {
+		return false, nil
+	}
+
+	s.ids[id] = expires
+
+	return true, nil
+}
+
+// Release implements SeenStore.
+func (s *MemorySeenStore) Release(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.ids, id)
+
+	return nil
+}
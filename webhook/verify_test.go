@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	now := time.Unix(1654000000, 0)
+	body := []byte(`{"id":"evt_1","type":"card.frozen","data":{"cardId":"c1"}}`)
+
+	v := NewVerifier("whsec_test")
+	v.Now = func() time.Time { return now }
+
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		wantErr error
+	}{
+		{name: "valid", header: Sign("whsec_test", now, body), body: body},
+		{name: "valid with a rotated secret", header: Sign("whsec_old", now, body) + ",v1=" + strings.SplitN(Sign("whsec_test", now, body), "v1=", 2)[1], body: body},
+		{name: "missing", header: "", body: body, wantErr: ErrMissingSignature},
+		{name: "malformed", header: "sha256=abc", body: body, wantErr: ErrInvalidSignature},
+		{name: "wrong secret", header: Sign("whsec_other", now, body), body: body, wantErr: ErrInvalidSignature},
+		{name: "tampered body", header: Sign("whsec_test", now, body), body: []byte(`{"id":"evt_1","type":"card.stopped"}`), wantErr: ErrInvalidSignature},
+		{name: "too old", header: Sign("whsec_test", now.Add(-6*time.Minute), body), body: body, wantErr: ErrTimestampExpired},
+		{name: "too far in the future", header: Sign("whsec_test", now.Add(6*time.Minute), body), body: body, wantErr: ErrTimestampExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Verify(tt.header, tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandler_Verification(t *testing.T) {
+	calls := 0
+	fail := true
+	h := NewHandler()
+	h.Verifier = NewVerifier("whsec_test")
+	h.OnCardFrozen(func(ctx context.Context, e CardStatusEvent) error {
+		calls++
+		if fail {
+			fail = false
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+
+	body := `{"id":"evt_1","type":"card.frozen","data":{"cardId":"c1"}}`
+	send := func(signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if signature != "" {
+			req.Header.Set(SignatureHeader, signature)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := send(""); code != http.StatusUnauthorized {
+		t.Errorf("unsigned status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := send(Sign("whsec_forged", time.Now(), []byte(body))); code != http.StatusUnauthorized {
+		t.Errorf("forged status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if calls != 0 {
+		t.Fatalf("rejected requests were dispatched")
+	}
+
+	signature := Sign("whsec_test", time.Now(), []byte(body))
+	if code := send(signature); code != http.StatusInternalServerError {
+		t.Errorf("failing status = %d, want %d", code, http.StatusInternalServerError)
+	}
+	if code := send(signature); code != http.StatusOK {
+		t.Errorf("redelivery status = %d, want %d", code, http.StatusOK)
+	}
+	if code := send(signature); code != http.StatusOK {
+		t.Errorf("replay status = %d, want %d", code, http.StatusOK)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want the redelivery dispatched and the replay dropped", calls)
+	}
+}
+
+func TestHandler_ConcurrentReplay(t *testing.T) {
+	var calls int32
+	entered := make(chan struct{})
+	proceed := make(chan struct{})
+
+	h := NewHandler()
+	h.Verifier = NewVerifier("whsec_test")
+	h.OnCardFrozen(func(ctx context.Context, e CardStatusEvent) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(entered)
+			<-proceed
+		}
+		return nil
+	})
+
+	body := `{"id":"evt_1","type":"card.frozen","data":{"cardId":"c1"}}`
+	signature := Sign("whsec_test", time.Now(), []byte(body))
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(SignatureHeader, signature)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	first := make(chan int)
+	go func() { first <- send() }()
+
+	// the second delivery arrives while the first is still being handled
+	<-entered
+	if code := send(); code != http.StatusOK {
+		t.Errorf("concurrent replay status = %d, want %d", code, http.StatusOK)
+	}
+	close(proceed)
+
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("first delivery status = %d, want %d", code, http.StatusOK)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want the concurrent replay dropped", calls)
+	}
+}
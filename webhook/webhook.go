@@ -0,0 +1,309 @@
+// Package webhook receives the events One Liquidity posts to the webhook URL
+// registered with RegisterIntegrator or UpdateWebhook.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	er "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+// Event types sent by One Liquidity.
+const (
+	EventCardCreated           = "card.created"
+	EventTransactionAuthorised = "transaction.authorised"
+	EventTransactionDeclined   = "transaction.declined"
+	EventDepositConfirmed      = "deposit.confirmed"
+	EventKYCStatusChanged      = "kyc.status_changed"
+	EventCardFrozen            = "card.frozen"
+	EventCardStopped           = "card.stopped"
+)
+
+// DefaultMaxBodyBytes caps the size of an event accepted by Handler.
+const DefaultMaxBodyBytes = 1 << 20
+
+// ErrInvalidEvent is returned by Parse for payloads that are not events.
+var ErrInvalidEvent = er.New("webhook: invalid event")
+
+// Event is the envelope shared by all events. Data holds the type-specific
+// payload; the typed events below decode it.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt string          `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// CardCreatedEvent is sent when a card has been issued.
+type CardCreatedEvent struct {
+	Event
+	Card liquidity.D2
+}
+
+// TransactionEvent is sent when a card transaction is authorised or declined.
+type TransactionEvent struct {
+	Event
+	Transaction liquidity.D4
+}
+
+// DepositConfirmedEvent is sent when a float deposit has been received.
+type DepositConfirmedEvent struct {
+	Event
+	Deposit liquidity.D3
+}
+
+// KYCStatus is the payload of a KYC status change.
+type KYCStatus struct {
+	UserID         string `json:"userId"`
+	SelfieUploaded bool   `json:"selfieUploaded"`
+	IDUploaded     bool   `json:"idUploaded"`
+	OfacChecked    bool   `json:"ofacChecked"`
+	OfacFail       bool   `json:"ofacFail"`
+	Active         bool   `json:"active"`
+}
+
+// KYCStatusChangedEvent is sent when a user's KYC checks progress.
+type KYCStatusChangedEvent struct {
+	Event
+	KYC KYCStatus
+}
+
+// CardStatus is the payload of a card being frozen or stopped.
+type CardStatus struct {
+	CardID   string `json:"cardId"`
+	Status   string `json:"status"`
+	ReasonID int    `json:"reasonId,omitempty"`
+}
+
+// CardStatusEvent is sent when a card is frozen or stopped.
+type CardStatusEvent struct {
+	Event
+	Card CardStatus
+}
+
+// Parse decodes an event envelope.
+func Parse(body []byte) (Event, error) {
+	var e Event
+	if err := json.Unmarshal(body, &e); err != nil {
+		return e, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+
+	if e.Type == "" {
+		return e, fmt.Errorf("%w: missing type", ErrInvalidEvent)
+	}
+
+	return e, nil
+}
+
+// decodeData unmarshals the event payload into v.
+func (e Event) decodeData(v interface{}) error {
+	if len(e.Data) == 0 {
+		return fmt.Errorf("%w: %s has no data", ErrInvalidEvent, e.Type)
+	}
+
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidEvent, e.Type, err)
+	}
+
+	return nil
+}
+
+// HandlerFunc handles one event. Returning an error makes Handler respond
+// with a 500 so that One Liquidity delivers the event again.
+type HandlerFunc func(ctx context.Context, e Event) error
+
+// Handler is an http.Handler that decodes events and dispatches them to the
+// functions registered for their type. Events without a registered function
+// are acknowledged and dropped.
+type Handler struct {
+	// MaxBodyBytes caps the size of a request body. Zero means
+	// DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// Verifier, when set, rejects requests without a valid recent signature
+	// with a 401, and acknowledges events it has already processed without
+	// dispatching them again.
+	Verifier *Verifier
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+}
+
+// NewHandler returns a Handler with no registered functions.
+func NewHandler() *Handler {
+	return &Handler{handlers: make(map[string]HandlerFunc)}
+}
+
+// Handle registers fn for events of the given type, replacing any function
+// registered before.
+func (h *Handler) Handle(eventType string, fn HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.handlers[eventType] = fn
+}
+
+// OnCardCreated registers fn for card.created events.
+func (h *Handler) OnCardCreated(fn func(ctx context.Context, e CardCreatedEvent) error) {
+	h.Handle(EventCardCreated, func(ctx context.Context, e Event) error {
+		ev := CardCreatedEvent{Event: e}
+		if err := e.decodeData(&ev.Card); err != nil {
+			return err
+		}
+		return fn(ctx, ev)
+	})
+}
+
+// OnTransactionAuthorised registers fn for transaction.authorised events.
+func (h *Handler) OnTransactionAuthorised(fn func(ctx context.Context, e TransactionEvent) error) {
+	h.Handle(EventTransactionAuthorised, transactionHandler(fn))
+}
+
+// OnTransactionDeclined registers fn for transaction.declined events.
+func (h *Handler) OnTransactionDeclined(fn func(ctx context.Context, e TransactionEvent) error) {
+	h.Handle(EventTransactionDeclined, transactionHandler(fn))
+}
+
+// OnDepositConfirmed registers fn for deposit.confirmed events.
+func (h *Handler) OnDepositConfirmed(fn func(ctx context.Context, e DepositConfirmedEvent) error) {
+	h.Handle(EventDepositConfirmed, func(ctx context.Context, e Event) error {
+		ev := DepositConfirmedEvent{Event: e}
+		if err := e.decodeData(&ev.Deposit); err != nil {
+			return err
+		}
+		return fn(ctx, ev)
+	})
+}
+
+// OnKYCStatusChanged registers fn for kyc.status_changed events.
+func (h *Handler) OnKYCStatusChanged(fn func(ctx context.Context, e KYCStatusChangedEvent) error) {
+	h.Handle(EventKYCStatusChanged, func(ctx context.Context, e Event) error {
+		ev := KYCStatusChangedEvent{Event: e}
+		if err := e.decodeData(&ev.KYC); err != nil {
+			return err
+		}
+		return fn(ctx, ev)
+	})
+}
+
+// OnCardFrozen registers fn for card.frozen events.
+func (h *Handler) OnCardFrozen(fn func(ctx context.Context, e CardStatusEvent) error) {
+	h.Handle(EventCardFrozen, cardStatusHandler(fn))
+}
+
+// OnCardStopped registers fn for card.stopped events.
+func (h *Handler) OnCardStopped(fn func(ctx context.Context, e CardStatusEvent) error) {
+	h.Handle(EventCardStopped, cardStatusHandler(fn))
+}
+
+func transactionHandler(fn func(ctx context.Context, e TransactionEvent) error) HandlerFunc {
+	return func(ctx context.Context, e Event) error {
+		ev := TransactionEvent{Event: e}
+		if err := e.decodeData(&ev.Transaction); err != nil {
+			return err
+		}
+		return fn(ctx, ev)
+	}
+}
+
+func cardStatusHandler(fn func(ctx context.Context, e CardStatusEvent) error) HandlerFunc {
+	return func(ctx context.Context, e Event) error {
+		ev := CardStatusEvent{Event: e}
+		if err := e.decodeData(&ev.Card); err != nil {
+			return err
+		}
+		return fn(ctx, ev)
+	}
+}
+
+// ServeHTTP decodes the event in r and dispatches it.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := h.MaxBodyBytes
+	if limit <= 0 {
+		limit = DefaultMaxBodyBytes
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		http.Error(w, "unable to read body", http.StatusBadRequest)
+		return
+	}
+
+	if int64(len(body)) > limit {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var signed time.Time
+	if h.Verifier != nil {
+		if signed, err = h.Verifier.Verify(r.Header.Get(SignatureHeader), body); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	e, err := Parse(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var replayKey string
+	if h.Verifier != nil && h.Verifier.Store != nil {
+		replayKey = e.ID
+		if replayKey == "" {
+			replayKey = r.Header.Get(SignatureHeader)
+		}
+
+		claimed, err := h.Verifier.Store.Claim(replayKey, signed.Add(h.Verifier.tolerance()))
+		if err != nil {
+			http.Error(w, "unable to check for replays", http.StatusInternalServerError)
+			return
+		}
+
+		if !claimed {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	if err := h.dispatch(r.Context(), e); err != nil {
+		// give the claim back so the provider's retry is dispatched
+		if replayKey != "" {
+			_ = h.Verifier.Store.Release(replayKey)
+		}
+
+		if er.Is(err, ErrInvalidEvent) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "event handler failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) dispatch(ctx context.Context, e Event) error {
+	h.mu.RLock()
+	fn, ok := h.handlers[e.Type]
+	h.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	return fn(ctx, e)
+}
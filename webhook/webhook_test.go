@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	liquidity "github.com/bushaHQ/one-liquidity-go"
+)
+
+func post(h http.Handler, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/liquidity", strings.NewReader(body)))
+	return rec
+}
+
+func TestHandler_Dispatch(t *testing.T) {
+	h := NewHandler()
+
+	var card liquidity.D2
+	h.OnCardCreated(func(ctx context.Context, e CardCreatedEvent) error {
+		card = e.Card
+		return nil
+	})
+
+	var declined TransactionEvent
+	h.OnTransactionDeclined(func(ctx context.Context, e TransactionEvent) error {
+		declined = e
+		return nil
+	})
+
+	var kyc KYCStatus
+	h.OnKYCStatusChanged(func(ctx context.Context, e KYCStatusChangedEvent) error {
+		kyc = e.KYC
+		return nil
+	})
+
+	var stopped CardStatus
+	h.OnCardStopped(func(ctx context.Context, e CardStatusEvent) error {
+		stopped = e.Card
+		return nil
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		check  func() bool
+	}{
+		{
+			name:   "card created",
+			body:   `{"id":"evt_1","type":"card.created","data":{"cardId":"c954e4c9","balance":0,"currency":"USD"}}`,
+			status: http.StatusOK,
+			check:  func() bool { return card.CardId == "c954e4c9" && card.Balance.Currency == "USD" },
+		},
+		{
+			name:   "transaction declined",
+			body:   `{"id":"evt_2","type":"transaction.declined","data":{"transactionId":"t1","amount":12.5,"currency":"USD","errorDescription":"Insufficient funds"}}`,
+			status: http.StatusOK,
+			check: func() bool {
+				return declined.ID == "evt_2" && declined.Transaction.Amount.Equal(liquidity.NewMoney(1250, "USD"))
+			},
+		},
+		{
+			name:   "kyc status changed",
+			body:   `{"id":"evt_3","type":"kyc.status_changed","data":{"userId":"u1","ofacChecked":true,"active":true}}`,
+			status: http.StatusOK,
+			check:  func() bool { return kyc.UserID == "u1" && kyc.OfacChecked && kyc.Active },
+		},
+		{
+			name:   "card stopped",
+			body:   `{"id":"evt_4","type":"card.stopped","data":{"cardId":"c1","status":"stopped","reasonId":2}}`,
+			status: http.StatusOK,
+			check:  func() bool { return stopped.CardID == "c1" && stopped.ReasonID == 2 },
+		},
+		{
+			name:   "unhandled types are acknowledged",
+			body:   `{"id":"evt_5","type":"deposit.confirmed","data":{}}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "malformed JSON",
+			body:   `{"id":`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "missing type",
+			body:   `{"id":"evt_6"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "payload of the wrong shape",
+			body:   `{"id":"evt_7","type":"card.created","data":"nope"}`,
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(h, tt.body)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.check != nil && !tt.check() {
+				t.Errorf("handler did not receive the decoded event")
+			}
+		})
+	}
+}
+
+func TestHandler_HandlerError(t *testing.T) {
+	h := NewHandler()
+	h.OnDepositConfirmed(func(ctx context.Context, e DepositConfirmedEvent) error {
+		return errors.New("ledger unavailable")
+	})
+
+	rec := post(h, `{"id":"evt_1","type":"deposit.confirmed","data":{"depositId":"d1","amount":100,"currency":"USD"}}`)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestHandler_Limits(t *testing.T) {
+	h := NewHandler()
+	h.MaxBodyBytes = 16
+
+	rec := post(h, `{"id":"evt_1","type":"card.created","data":{}}`)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}